@@ -55,3 +55,8 @@ func (c *Client) Set(
 ) error {
 	return c.Redis.Set(ctx, key, value, timeout).Err()
 }
+
+// Delete deletes the values stored on `keys`
+func (c *Client) Delete(ctx context.Context, keys ...string) error {
+	return c.Redis.Del(ctx, keys...).Err()
+}
@@ -9,4 +9,5 @@ import (
 type Service interface {
 	Get(context.Context, string) ([]byte, error)
 	Set(context.Context, string, interface{}, time.Duration) error
+	Delete(context.Context, ...string) error
 }
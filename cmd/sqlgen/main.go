@@ -0,0 +1,62 @@
+// Command sqlgen generates typed Go functions calling db.Service from the queries
+// of sql files. It's meant to be run by `go generate`, next to the embedded files:
+//
+//	//go:generate go run github.com/agflow/tools/cmd/sqlgen -dir sql -out queries_gen.go
+//
+// See the sql/gen package for the header comments describing each query
+package main
+
+import (
+	"flag"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/agflow/tools/log"
+	"github.com/agflow/tools/sql/gen"
+)
+
+// imports collects the repeated `-import name=path` flags
+type imports map[string]string
+
+func (i imports) String() string {
+	return ""
+}
+
+func (i imports) Set(value string) error {
+	name, p, ok := strings.Cut(value, "=")
+	if !ok {
+		return errors.Errorf("invalid import %q, expected name=path", value)
+	}
+	i[name] = p
+	return nil
+}
+
+func main() {
+	opts := gen.Options{Imports: imports{}}
+	dir := flag.String("dir", ".", "directory of the sql files")
+	out := flag.String("out", "queries_gen.go", "generated file")
+	flag.StringVar(&opts.Package, "pkg", os.Getenv("GOPACKAGE"), "package of the generated file")
+	flag.Var(imports(opts.Imports), "import", "import path of a type package, as name=path")
+	flag.Parse()
+
+	if err := run(*dir, *out, opts); err != nil {
+		log.Fatal(errors.Wrap(err, "can't generate queries"))
+	}
+}
+
+func run(dir, out string, opts gen.Options) error {
+	if opts.Package == "" {
+		return errors.New("missing package name")
+	}
+	queries, err := gen.Load(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+	src, err := gen.Generate(queries, opts)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(out, src, 0o644)
+}
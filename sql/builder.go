@@ -0,0 +1,156 @@
+package sql
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/agflow/tools/sql/internal/lexer"
+)
+
+// Builder builds a SELECT query with postgres `$n` placeholders, ready for
+// db.Service.Select. Conditions use `?` as placeholder, and `??` for a literal `?`.
+// Only conditions have placeholders, `?` is kept as it is in quoted text, comments
+// and the other clauses
+type Builder struct {
+	columns []string
+	from    string
+	where   []string
+	args    []interface{}
+	orderBy []string
+	limit   int
+	offset  int
+}
+
+// Select starts building a query selecting `columns`, or every column when empty
+func Select(columns ...string) *Builder {
+	return &Builder{columns: columns}
+}
+
+// From sets the table, or any from item, queried
+func (b *Builder) From(from string) *Builder {
+	b.from = from
+	return b
+}
+
+// Where adds the condition `cond`, joined to the previous ones with AND. Conditions
+// are wrapped in parentheses when joined, so that they can contain OR
+func (b *Builder) Where(cond string, args ...interface{}) *Builder {
+	b.where = append(b.where, cond)
+	b.args = append(b.args, args...)
+	return b
+}
+
+// And is an alias of Where
+func (b *Builder) And(cond string, args ...interface{}) *Builder {
+	return b.Where(cond, args...)
+}
+
+// Or joins the condition `cond` to the last one with OR
+func (b *Builder) Or(cond string, args ...interface{}) *Builder {
+	if len(b.where) == 0 {
+		return b.Where(cond, args...)
+	}
+	last := len(b.where) - 1
+	b.where[last] += " OR " + cond
+	b.args = append(b.args, args...)
+	return b
+}
+
+// In adds the condition `column IN (values...)`, where `values` is a slice.
+// An empty slice matches no rows, and a []byte is a single value
+func (b *Builder) In(column string, values interface{}) *Builder {
+	v := reflect.ValueOf(values)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array ||
+		v.Type().Elem().Kind() == reflect.Uint8 {
+		return b.Where(column+" = ?", values)
+	}
+	if v.Len() == 0 {
+		return b.Where("FALSE")
+	}
+	args := make([]interface{}, v.Len())
+	for i := range args {
+		args[i] = v.Index(i).Interface()
+	}
+	placeholders := strings.Repeat(", ?", len(args))[2:]
+	return b.Where(column+" IN ("+placeholders+")", args...)
+}
+
+// OrderBy adds `exprs`, like `price DESC`, to the ORDER BY clause
+func (b *Builder) OrderBy(exprs ...string) *Builder {
+	b.orderBy = append(b.orderBy, exprs...)
+	return b
+}
+
+// Limit sets the maximum number of rows returned, zero means no limit
+func (b *Builder) Limit(limit int) *Builder {
+	b.limit = limit
+	return b
+}
+
+// Offset sets the number of rows skipped
+func (b *Builder) Offset(offset int) *Builder {
+	b.offset = offset
+	return b
+}
+
+// Build returns the query and its arguments
+func (b *Builder) Build() (string, []interface{}) {
+	var q strings.Builder
+	q.WriteString("SELECT ")
+	if len(b.columns) == 0 {
+		q.WriteString("*")
+	} else {
+		q.WriteString(strings.Join(b.columns, ", "))
+	}
+	if b.from != "" {
+		q.WriteString(" FROM " + b.from)
+	}
+	where := make([]string, len(b.where))
+	n := 0
+	for i, cond := range b.where {
+		where[i], n = numberPlaceholders(cond, n)
+	}
+	switch len(where) {
+	case 0:
+	case 1:
+		q.WriteString(" WHERE " + where[0])
+	default:
+		q.WriteString(" WHERE (" + strings.Join(where, ") AND (") + ")")
+	}
+	if len(b.orderBy) > 0 {
+		q.WriteString(" ORDER BY " + strings.Join(b.orderBy, ", "))
+	}
+	if b.limit > 0 {
+		q.WriteString(" LIMIT " + strconv.Itoa(b.limit))
+	}
+	if b.offset > 0 {
+		q.WriteString(" OFFSET " + strconv.Itoa(b.offset))
+	}
+	return q.String(), append([]interface{}(nil), b.args...)
+}
+
+// numberPlaceholders replaces each `?` of `cond` with `$n`, counting from the `n`
+// previous placeholders, and `??` with `?`. It returns the number of placeholders
+func numberPlaceholders(cond string, n int) (string, int) {
+	var b strings.Builder
+	for i := 0; i < len(cond); i++ {
+		if skip := lexer.Skip(cond, i); skip > 0 {
+			b.WriteString(cond[i : i+skip])
+			i += skip - 1
+			continue
+		}
+		if cond[i] != '?' {
+			b.WriteByte(cond[i])
+			continue
+		}
+		if i+1 < len(cond) && cond[i+1] == '?' {
+			b.WriteByte('?')
+			i++
+			continue
+		}
+		n++
+		b.WriteString("$" + strconv.Itoa(n))
+	}
+	return b.String(), n
+}
@@ -0,0 +1,43 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuilder(t *testing.T) {
+	query, args := Select("id", "price").
+		From("prices").
+		Where("region = ?", "br").
+		Or("region = ?", "us").
+		And("tags ?? 'spot'").
+		In("product_id", []int64{1, 2}).
+		OrderBy("date DESC", "id").
+		Limit(10).
+		Offset(20).
+		Build()
+	require.Equal(t, "SELECT id, price FROM prices "+
+		"WHERE (region = $1 OR region = $2) AND (tags ? 'spot') AND (product_id IN ($3, $4)) "+
+		"ORDER BY date DESC, id LIMIT 10 OFFSET 20", query)
+	require.Equal(t, []interface{}{"br", "us", int64(1), int64(2)}, args)
+
+	query, args = Select().From("prices").
+		Where("a = ? OR b = ?", 1, 2).
+		And("c = ?", 3).
+		Build()
+	require.Equal(t, "SELECT * FROM prices WHERE (a = $1 OR b = $2) AND (c = $3)", query)
+	require.Equal(t, []interface{}{1, 2, 3}, args)
+
+	query, args = Select("'what?' AS q").From("prices").
+		Where("note <> 'why?' AND id = ?", 1).
+		In("raw", []byte("ab")).
+		Build()
+	require.Equal(t, "SELECT 'what?' AS q FROM prices "+
+		"WHERE (note <> 'why?' AND id = $1) AND (raw = $2)", query)
+	require.Equal(t, []interface{}{1, []byte("ab")}, args)
+
+	query, args = Select().From("prices").In("id", []int64{}).Build()
+	require.Equal(t, "SELECT * FROM prices WHERE FALSE", query)
+	require.Empty(t, args)
+}
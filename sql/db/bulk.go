@@ -0,0 +1,247 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/lib/pq"
+
+	"github.com/agflow/tools/log"
+	"github.com/agflow/tools/typing"
+)
+
+const (
+	// DefaultBatchSize is the number of rows written per INSERT by BulkInsert
+	DefaultBatchSize = 1000
+	// maxParams is the maximum number of parameters postgres accepts on a statement
+	maxParams = 65535
+)
+
+// BulkOptions configures how BulkInsert writes rows
+type BulkOptions struct {
+	// BatchSize is the number of rows written by each INSERT statement.
+	// It defaults to DefaultBatchSize and it's capped so that a statement
+	// doesn't have more parameters than postgres allows
+	BatchSize int
+	// Copy writes rows with COPY FROM STDIN instead of INSERT statements.
+	// It can't be used together with OnConflict
+	Copy bool
+	// OnConflict are the columns of the conflict target. When set, rows are upserted
+	OnConflict []string
+	// Update are the columns updated on conflict. It defaults to every column
+	// not in OnConflict, if there is none the conflicting rows are skipped
+	Update []string
+}
+
+// bulkConn is implemented by sql.Tx and sqlx.Tx
+type bulkConn interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	PrepareContext(context.Context, string) (*sql.Stmt, error)
+}
+
+// bulkInsert writes `rows`, a slice of structs mapped by `m`, into `table`
+// and returns the number of rows written
+func bulkInsert(
+	ctx context.Context, m *Mapper, conn bulkConn, table string, rows interface{},
+	opts BulkOptions,
+) (int64, error) {
+	value := reflect.Indirect(reflect.ValueOf(rows))
+	if value.Kind() != reflect.Slice {
+		return 0, fmt.Errorf("expected %s but got %s", reflect.Slice, value.Kind())
+	}
+	base, err := typing.Base(value.Type().Elem(), reflect.Struct)
+	if err != nil {
+		return 0, err
+	}
+	fields, err := m.writeFields(base)
+	if err != nil {
+		return 0, err
+	}
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("struct %s has no fields tagged with `db`", base.Name())
+	}
+	if value.Len() == 0 {
+		return 0, nil
+	}
+
+	if opts.Copy {
+		if len(opts.OnConflict) > 0 {
+			return 0, errors.New("COPY can't be used to upsert rows")
+		}
+		return copyIn(ctx, conn, table, value, fields)
+	}
+	return insertBatches(ctx, conn, table, value, fields, opts)
+}
+
+// writeFields lists the fields of `t` written by BulkInsert, which are only the
+// fields tagged with `db`. Inline structs are rejected, as their prefixed columns
+// can't be table columns
+func (m *Mapper) writeFields(t reflect.Type) ([]field, error) {
+	var fields []field
+	for _, f := range m.dominantFields(t) {
+		if !f.tagged {
+			continue
+		}
+		if strings.Contains(f.column, ".") {
+			return nil, fmt.Errorf("column %q of an inline struct of %s can't be written", f.column, t)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+func rowValues(v reflect.Value, fields []field) []interface{} {
+	v = reflect.Indirect(v)
+	values := make([]interface{}, len(fields))
+	for i := range fields {
+		if f, ok := fieldValue(v, fields[i].index); ok {
+			values[i] = f.Interface()
+		}
+	}
+	return values
+}
+
+func copyIn(
+	ctx context.Context, conn bulkConn, table string, rows reflect.Value, fields []field,
+) (int64, error) {
+	columns := make([]string, len(fields))
+	for i := range fields {
+		columns[i] = fields[i].column
+	}
+	stmt, err := conn.PrepareContext(ctx, copyQuery(table, columns))
+	if err != nil {
+		return 0, err
+	}
+	defer func() { log.ErrorType(stmt.Close()) }()
+
+	for i := 0; i < rows.Len(); i++ {
+		if _, err := stmt.ExecContext(ctx, rowValues(rows.Index(i), fields)...); err != nil {
+			return 0, err
+		}
+	}
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func insertBatches(
+	ctx context.Context, conn bulkConn, table string, rows reflect.Value, fields []field,
+	opts BulkOptions,
+) (int64, error) {
+	size := opts.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	if size > maxParams/len(fields) {
+		size = maxParams / len(fields)
+	}
+
+	var total int64
+	for start := 0; start < rows.Len(); start += size {
+		end := start + size
+		if end > rows.Len() {
+			end = rows.Len()
+		}
+		query, args := insertQuery(table, rows.Slice(start, end), fields, opts)
+		res, err := conn.ExecContext(ctx, query, args...)
+		if err != nil {
+			return total, err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// copyQuery returns the COPY statement writing `columns` into `table`, which can be
+// qualified by its schema like `schema.table`
+func copyQuery(table string, columns []string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return pq.CopyInSchema(schema, name, columns...)
+	}
+	return pq.CopyIn(table, columns...)
+}
+
+// quoteTable quotes `table` like copyQuery does
+func quoteTable(table string) string {
+	if schema, name, ok := strings.Cut(table, "."); ok {
+		return pq.QuoteIdentifier(schema) + "." + pq.QuoteIdentifier(name)
+	}
+	return pq.QuoteIdentifier(table)
+}
+
+func quoteIdentifiers(names []string) string {
+	quoted := make([]string, len(names))
+	for i := range names {
+		quoted[i] = pq.QuoteIdentifier(names[i])
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// insertQuery builds a multi-row INSERT of `rows` with its arguments
+func insertQuery(
+	table string, rows reflect.Value, fields []field, opts BulkOptions,
+) (string, []interface{}) {
+	columns := make([]string, len(fields))
+	for i := range fields {
+		columns[i] = fields[i].column
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "INSERT INTO %s (%s) VALUES ", quoteTable(table), quoteIdentifiers(columns))
+	args := make([]interface{}, 0, rows.Len()*len(fields))
+	for i := 0; i < rows.Len(); i++ {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("(")
+		for j := range fields {
+			if j > 0 {
+				b.WriteString(", ")
+			}
+			fmt.Fprintf(&b, "$%d", len(args)+j+1)
+		}
+		b.WriteString(")")
+		args = append(args, rowValues(rows.Index(i), fields)...)
+	}
+
+	if len(opts.OnConflict) > 0 {
+		b.WriteString(onConflict(columns, opts))
+	}
+	return b.String(), args
+}
+
+func onConflict(columns []string, opts BulkOptions) string {
+	update := opts.Update
+	if len(update) == 0 {
+		conflict := make(map[string]bool, len(opts.OnConflict))
+		for _, c := range opts.OnConflict {
+			conflict[c] = true
+		}
+		for _, c := range columns {
+			if !conflict[c] {
+				update = append(update, c)
+			}
+		}
+	}
+
+	target := quoteIdentifiers(opts.OnConflict)
+	if len(update) == 0 {
+		return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", target)
+	}
+	sets := make([]string, len(update))
+	for i, c := range update {
+		c = pq.QuoteIdentifier(c)
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", c, c)
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", target, strings.Join(sets, ", "))
+}
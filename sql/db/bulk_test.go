@@ -0,0 +1,84 @@
+package db
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type bulkPrice struct {
+	ID     int64   `db:"id"`
+	Region string  `db:"region"`
+	Price  float64 `db:"price"`
+}
+
+func TestInsertQuery(t *testing.T) {
+	rows := reflect.ValueOf([]bulkPrice{{1, "br", 10.5}, {2, "us", 11}})
+	fields := defaultMapper.structFields(reflect.TypeOf(bulkPrice{}), "")
+
+	testCases := []struct {
+		opts     BulkOptions
+		expected string
+	}{
+		{
+			expected: `INSERT INTO "prices" ("id", "region", "price") ` +
+				`VALUES ($1, $2, $3), ($4, $5, $6)`,
+		},
+		{
+			opts: BulkOptions{OnConflict: []string{"id"}},
+			expected: `INSERT INTO "prices" ("id", "region", "price") ` +
+				`VALUES ($1, $2, $3), ($4, $5, $6) ON CONFLICT ("id") ` +
+				`DO UPDATE SET "region" = EXCLUDED."region", "price" = EXCLUDED."price"`,
+		},
+		{
+			opts: BulkOptions{OnConflict: []string{"id", "region", "price"}},
+			expected: `INSERT INTO "prices" ("id", "region", "price") ` +
+				`VALUES ($1, $2, $3), ($4, $5, $6) ON CONFLICT ("id", "region", "price") ` +
+				`DO NOTHING`,
+		},
+	}
+
+	for _, testCase := range testCases {
+		query, args := insertQuery("prices", rows, fields, testCase.opts)
+		require.Equal(t, testCase.expected, query)
+		require.Equal(t, []interface{}{int64(1), "br", 10.5, int64(2), "us", 11.0}, args)
+	}
+}
+
+func TestQuoteTable(t *testing.T) {
+	rows := reflect.ValueOf([]bulkPrice{{1, "br", 10.5}})
+	fields := defaultMapper.structFields(reflect.TypeOf(bulkPrice{}), "")
+	query, _ := insertQuery("market.prices", rows, fields, BulkOptions{})
+	require.Equal(t, `INSERT INTO "market"."prices" ("id", "region", "price") VALUES ($1, $2, $3)`,
+		query)
+
+	require.Equal(t, `COPY "market"."prices" ("id", "region") FROM STDIN`,
+		copyQuery("market.prices", []string{"id", "region"}))
+	require.Equal(t, `COPY "prices" ("id", "region") FROM STDIN`,
+		copyQuery("prices", []string{"id", "region"}))
+
+	_, err := bulkInsert(context.Background(), defaultMapper, nil, "prices", []bulkPrice{{}},
+		BulkOptions{Copy: true, OnConflict: []string{"id"}})
+	require.EqualError(t, err, "COPY can't be used to upsert rows")
+}
+
+func TestWriteFields(t *testing.T) {
+	type auditedPrice struct {
+		*Audit
+		ID       int64 `db:"id"`
+		PriceUSD float64
+		Ignored  string `db:"-"`
+	}
+	fields, err := defaultMapper.writeFields(reflect.TypeOf(auditedPrice{}))
+	require.NoError(t, err)
+	require.Equal(t, []field{
+		{column: "updated_by", index: []int{0, 1}, tagged: true},
+		{column: "id", index: []int{1}, tagged: true},
+	}, fields)
+
+	_, err = defaultMapper.writeFields(reflect.TypeOf(trade{}))
+	require.EqualError(t, err,
+		`column "buyer.id" of an inline struct of db.trade can't be written`)
+}
@@ -0,0 +1,243 @@
+package dbtest
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/agflow/tools/sql/db"
+)
+
+// Statement is a query run on a Fake with its arguments
+type Statement struct {
+	Query string
+	Args  []interface{}
+}
+
+// Expectation is the canned result of the queries it matches
+type Expectation struct {
+	query   string
+	re      *regexp.Regexp
+	columns []string
+	rows    [][]interface{}
+	err     error
+	matched int
+}
+
+// WillReturnRows sets the rows returned by the matching queries
+func (e *Expectation) WillReturnRows(columns []string, rows ...[]interface{}) *Expectation {
+	e.columns = columns
+	e.rows = rows
+	return e
+}
+
+// WillReturnError sets the error returned by the matching queries
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+func (e *Expectation) matches(query string) bool {
+	if e.re != nil {
+		return e.re.MatchString(query)
+	}
+	return e.query == normalize(query)
+}
+
+func (e *Expectation) String() string {
+	if e.re != nil {
+		return e.re.String()
+	}
+	return e.query
+}
+
+// normalize collapses the whitespace of `query`
+func normalize(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
+
+// Fake is a db.Service answering queries with the results of the first matching
+// expectation. Rows are scanned with the same rules as db.Select. Queries
+// returning rows fail when no expectation matches them, while statements
+// like Exec succeed
+type Fake struct {
+	mu           sync.Mutex
+	expectations []*Expectation
+	executed     []Statement
+}
+
+// New returns a new Fake without expectations
+func New() *Fake {
+	return &Fake{}
+}
+
+// Expect adds an expectation matching `query` exactly, ignoring whitespace
+func (f *Fake) Expect(query string) *Expectation {
+	return f.add(&Expectation{query: normalize(query)})
+}
+
+// ExpectRegexp adds an expectation matching queries with the regular expression `pattern`
+func (f *Fake) ExpectRegexp(pattern string) *Expectation {
+	return f.add(&Expectation{re: regexp.MustCompile(pattern)})
+}
+
+func (f *Fake) add(e *Expectation) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.expectations = append(f.expectations, e)
+	return e
+}
+
+// Executed returns every statement run on the fake, in order
+func (f *Fake) Executed() []Statement {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Statement(nil), f.executed...)
+}
+
+// Unmatched returns an error listing the expectations that matched no query
+func (f *Fake) Unmatched() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var unmatched []string
+	for _, e := range f.expectations {
+		if e.matched == 0 {
+			unmatched = append(unmatched, e.String())
+		}
+	}
+	if len(unmatched) > 0 {
+		return fmt.Errorf("expectations not matched: %s", strings.Join(unmatched, "; "))
+	}
+	return nil
+}
+
+// run records `query` and returns its expectation, if there is one
+func (f *Fake) run(query string, args []interface{}) *Expectation {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.executed = append(f.executed, Statement{Query: query, Args: args})
+	for _, e := range f.expectations {
+		if e.matches(query) {
+			e.matched++
+			return e
+		}
+	}
+	return nil
+}
+
+// query returns the rows of the expectation matching `query`
+func (f *Fake) query(query string, args []interface{}) (*rows, error) {
+	e := f.run(query, args)
+	if e == nil {
+		return nil, fmt.Errorf("no expectation matches query %q", normalize(query))
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &rows{columns: e.columns, values: e.rows, i: -1}, nil
+}
+
+// exec returns the error of the expectation matching `query`
+func (f *Fake) exec(query string, args []interface{}) error {
+	if e := f.run(query, args); e != nil {
+		return e.err
+	}
+	return nil
+}
+
+// Select fills `dest` with the rows expected for `query`
+func (f *Fake) Select(dest interface{}, query string, args ...interface{}) error {
+	r, err := f.query(query, args)
+	if err != nil {
+		return err
+	}
+	return db.ScanAll(r, dest)
+}
+
+// Get fills `dest` with the first row expected for `query`
+func (f *Fake) Get(dest interface{}, query string, args ...interface{}) error {
+	r, err := f.query(query, args)
+	if err != nil {
+		return err
+	}
+	return db.ScanOne(r, dest, false)
+}
+
+// GetUnique is like Get, but it fails when more than one row is expected
+func (f *Fake) GetUnique(dest interface{}, query string, args ...interface{}) error {
+	r, err := f.query(query, args)
+	if err != nil {
+		return err
+	}
+	return db.ScanOne(r, dest, true)
+}
+
+// Stream returns the rows expected for `query` to be scanned one at a time
+func (f *Fake) Stream(_ context.Context, query string, args ...interface{}) (db.Rows, error) {
+	r, err := f.query(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return db.NewRows(r)
+}
+
+// NamedSelect is like Select, expectations are matched against the unbound `query`
+func (f *Fake) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	_, args, err := db.Named(query, arg)
+	if err != nil {
+		return err
+	}
+	return f.Select(dest, query, args...)
+}
+
+// Exec records `query` and returns the error expected for it
+func (f *Fake) Exec(query string, args ...interface{}) error {
+	return f.exec(query, args)
+}
+
+// NamedExec is like Exec, expectations are matched against the unbound `query`
+func (f *Fake) NamedExec(query string, arg interface{}) error {
+	_, args, err := db.Named(query, arg)
+	if err != nil {
+		return err
+	}
+	return f.exec(query, args)
+}
+
+// BulkInsert records a `BULK INSERT INTO table` statement with `rows` as argument
+func (f *Fake) BulkInsert(
+	_ context.Context, table string, rows interface{}, _ db.BulkOptions,
+) (int64, error) {
+	if err := f.exec("BULK INSERT INTO "+table, []interface{}{rows}); err != nil {
+		return 0, err
+	}
+	v := reflect.Indirect(reflect.ValueOf(rows))
+	if v.Kind() != reflect.Slice {
+		return 0, fmt.Errorf("expected %s but got %s", reflect.Slice, v.Kind())
+	}
+	return int64(v.Len()), nil
+}
+
+// WithTx runs `fn` on the fake itself
+func (f *Fake) WithTx(_ context.Context, _ *sql.TxOptions, fn func(db.Service) error) error {
+	return fn(f)
+}
+
+// Ping always succeeds
+func (f *Fake) Ping(context.Context) error {
+	return nil
+}
+
+// Stats returns empty statistics
+func (f *Fake) Stats() sql.DBStats {
+	return sql.DBStats{}
+}
+
+// Close always succeeds
+func (f *Fake) Close() error {
+	return nil
+}
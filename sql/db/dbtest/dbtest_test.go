@@ -0,0 +1,58 @@
+package dbtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql/db"
+)
+
+type price struct {
+	ID     int64 `db:"id"`
+	Region string
+	Value  *float64 `db:"value"`
+}
+
+func TestFake(t *testing.T) {
+	fake := New()
+	fake.Expect("SELECT id, region, value FROM prices").
+		WillReturnRows([]string{"id", "region", "value"},
+			[]interface{}{int64(1), "br", 10.5},
+			[]interface{}{int64(2), []byte("us"), nil})
+	fake.ExpectRegexp(`^DELETE FROM prices`).WillReturnError(errors.New("denied"))
+	var svc db.Service = fake
+
+	var prices []price
+	require.NoError(t, svc.Select(&prices, `SELECT id, region, value
+		FROM prices`))
+	require.Len(t, prices, 2)
+	require.Equal(t, "br", prices[0].Region)
+	require.Equal(t, 10.5, *prices[0].Value)
+	require.Equal(t, "us", prices[1].Region)
+	require.Nil(t, prices[1].Value)
+
+	var p price
+	require.NoError(t, svc.Get(&p, "SELECT id, region, value FROM prices"))
+	require.Equal(t, int64(1), p.ID)
+	require.ErrorIs(t, svc.GetUnique(&p, "SELECT id, region, value FROM prices"),
+		db.ErrMultipleRows)
+
+	ids, err := db.Query[int64](context.Background(), svc, "SELECT id FROM prices")
+	require.Error(t, err)
+	require.Nil(t, ids)
+
+	require.EqualError(t, svc.Exec("DELETE FROM prices WHERE id = $1", 1), "denied")
+	require.NoError(t, svc.NamedExec("UPDATE prices SET value = :value", p))
+	require.Equal(t, []Statement{
+		{Query: "SELECT id, region, value\n\t\tFROM prices"},
+		{Query: "SELECT id, region, value FROM prices"},
+		{Query: "SELECT id, region, value FROM prices"},
+		{Query: "SELECT id FROM prices"},
+		{Query: "DELETE FROM prices WHERE id = $1", Args: []interface{}{1}},
+		{Query: "UPDATE prices SET value = :value", Args: []interface{}{p.Value}},
+	}, fake.Executed())
+	require.NoError(t, fake.Unmatched())
+}
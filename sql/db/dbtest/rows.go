@@ -0,0 +1,49 @@
+package dbtest
+
+import (
+	"errors"
+	"fmt"
+
+	agsql "github.com/agflow/tools/sql"
+)
+
+// rows is a db.RowSource of canned values
+type rows struct {
+	columns []string
+	values  [][]interface{}
+	i       int
+}
+
+func (r *rows) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *rows) Next() bool {
+	r.i++
+	return r.i < len(r.values)
+}
+
+func (r *rows) Scan(dest ...interface{}) error {
+	if r.i < 0 || r.i >= len(r.values) {
+		return errors.New("Scan called without calling Next")
+	}
+	row := r.values[r.i]
+	if len(dest) != len(row) {
+		return fmt.Errorf("expected %d destination arguments in Scan, not %d",
+			len(row), len(dest))
+	}
+	for i := range dest {
+		if err := agsql.Assign(dest[i], row[i]); err != nil {
+			return fmt.Errorf("can't scan column %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+func (r *rows) Err() error {
+	return nil
+}
+
+func (r *rows) Close() error {
+	return nil
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrUniqueViolation is a postgres unique_violation, 23505
+	ErrUniqueViolation = errors.New("unique violation")
+	// ErrForeignKeyViolation is a postgres foreign_key_violation, 23503
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	// ErrSerializationFailure is a postgres serialization_failure, 40001
+	ErrSerializationFailure = errors.New("serialization failure")
+	// ErrDeadlock is a postgres deadlock_detected, 40P01
+	ErrDeadlock = errors.New("deadlock detected")
+	// ErrTimeout is a postgres query_canceled, 57014, returned on statement
+	// timeouts, or a lock_not_available, 55P03, returned on lock timeouts
+	ErrTimeout = errors.New("timeout")
+)
+
+// errorsByCode are the sentinel errors by SQLSTATE code
+func errorsByCode(code string) error {
+	switch code {
+	case "23505":
+		return ErrUniqueViolation
+	case "23503":
+		return ErrForeignKeyViolation
+	case "40001":
+		return ErrSerializationFailure
+	case "40P01":
+		return ErrDeadlock
+	case "57014", "55P03":
+		return ErrTimeout
+	default:
+		return nil
+	}
+}
+
+// Error is a postgres error classified by its SQLSTATE code. It matches its
+// sentinel error, like ErrUniqueViolation, with errors.Is
+type Error struct {
+	// Code is the SQLSTATE code of the error
+	Code string
+	// Constraint is the name of the violated constraint, if any
+	Constraint string
+	// Kind is the sentinel error matching Code
+	Kind error
+	// Err is the error returned by the driver
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the error of the driver
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is matches the sentinel error of `e`
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+// translate turns the errors of the driver with a known SQLSTATE code into an
+// *Error, leaving any other error unchanged
+func translate(err error) error {
+	var e *Error
+	if err == nil || errors.As(err, &e) {
+		return err
+	}
+
+	var code, constraint string
+	var pqErr *pq.Error
+	var stateErr interface{ SQLState() string }
+	switch {
+	case errors.As(err, &pqErr):
+		code, constraint = string(pqErr.Code), pqErr.Constraint
+	case errors.As(err, &stateErr):
+		code = stateErr.SQLState()
+	default:
+		return err
+	}
+
+	kind := errorsByCode(code)
+	if kind == nil {
+		return err
+	}
+	return &Error{Code: code, Constraint: constraint, Kind: kind, Err: err}
+}
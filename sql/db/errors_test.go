@@ -0,0 +1,43 @@
+package db
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTranslate(t *testing.T) {
+	testCases := map[pq.ErrorCode]error{
+		"23505": ErrUniqueViolation,
+		"23503": ErrForeignKeyViolation,
+		"40001": ErrSerializationFailure,
+		"40P01": ErrDeadlock,
+		"57014": ErrTimeout,
+		"55P03": ErrTimeout,
+	}
+	for code, sentinel := range testCases {
+		pqErr := &pq.Error{Code: code, Constraint: "prices_pkey"}
+		err := translate(pqErr)
+		require.ErrorIs(t, err, sentinel, code)
+
+		var e *Error
+		require.True(t, errors.As(err, &e), code)
+		require.Equal(t, string(code), e.Code)
+		require.Equal(t, "prices_pkey", e.Constraint)
+
+		var driverErr *pq.Error
+		require.True(t, errors.As(err, &driverErr), code)
+		require.Same(t, pqErr, driverErr)
+
+		require.Same(t, e, translate(err))
+	}
+
+	unknown := &pq.Error{Code: "22P02"}
+	require.Same(t, unknown, translate(unknown))
+	for _, sentinel := range testCases {
+		require.False(t, errors.Is(translate(unknown), sentinel))
+	}
+	require.NoError(t, translate(nil))
+}
@@ -0,0 +1,256 @@
+package db
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/agflow/tools/typing"
+)
+
+// field is a struct field mapped to a column
+type field struct {
+	column string
+	index  []int
+	// tagged is set when the column is named by a `db` tag
+	tagged bool
+}
+
+// structFields lists the fields of `t` mapped to columns. Fields are named after
+// their `db` tag or the name mapper if they have none. Untagged structs, embedded
+// struct pointers and structs tagged with `db:"prefix,inline"` are walked to map
+// their own fields, which are prefixed by "prefix." when inlined
+func (m *Mapper) structFields(t reflect.Type, prefix string) []field {
+	var fields []field
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, opts, tagged := parseTag(f.Tag)
+		if !mappable(f) || name == "-" {
+			continue
+		}
+		if base, ok := walked(f, opts, tagged); ok {
+			nestedPrefix := prefix
+			if opts == "inline" && name != "" {
+				nestedPrefix = prefix + name + "."
+			}
+			for _, nested := range m.structFields(base, nestedPrefix) {
+				nested.index = append([]int{i}, nested.index...)
+				fields = append(fields, nested)
+			}
+			continue
+		}
+		if !tagged {
+			name = m.mapName(f.Name)
+		}
+		if name != "" {
+			fields = append(fields, field{column: prefix + name, index: []int{i}, tagged: tagged})
+		}
+	}
+	return fields
+}
+
+// dominantFields lists the fields of `t` like structFields, keeping a single field
+// by column. As with Go field selectors, the shallowest field wins, and a tagged
+// field wins over an untagged one at the same depth
+func (m *Mapper) dominantFields(t reflect.Type) []field {
+	var fields []field
+	byColumn := make(map[string]int)
+	for _, f := range m.structFields(t, "") {
+		i, ok := byColumn[f.column]
+		switch {
+		case !ok:
+			byColumn[f.column] = len(fields)
+			fields = append(fields, f)
+		case dominates(f, fields[i]):
+			fields[i] = f
+		}
+	}
+	return fields
+}
+
+// dominates returns whether `f` is mapped to its column instead of `other`
+func dominates(f, other field) bool {
+	if len(f.index) != len(other.index) {
+		return len(f.index) < len(other.index)
+	}
+	return f.tagged && !other.tagged
+}
+
+// mappable returns whether `f` is exported or an embedded struct
+func mappable(f reflect.StructField) bool {
+	return f.PkgPath == "" || f.Anonymous && f.Type.Kind() == reflect.Struct
+}
+
+// walked returns the struct type of `f` when its own fields are mapped instead of it
+func walked(f reflect.StructField, opts string, tagged bool) (reflect.Type, bool) {
+	base := typing.DeRef(f.Type)
+	if base.Kind() != reflect.Struct || isScannable(base) {
+		return nil, false
+	}
+	return base, opts == "inline" || !tagged && (f.Anonymous || f.Type.Kind() == reflect.Struct)
+}
+
+// parseTag splits the `db` tag of a field into its name and options
+func parseTag(tag reflect.StructTag) (name, opts string, ok bool) {
+	dbTag, ok := tag.Lookup("db")
+	name, opts, _ = strings.Cut(dbTag, ",")
+	return name, opts, ok
+}
+
+// fieldByIndex returns the field of `v` at `index`, allocating the nil
+// struct pointers it goes through
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
+// fieldValue returns the field of `v` at `index`. It returns false
+// when it goes through a nil struct pointer
+func fieldValue(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// ToSnakeCase converts a Go field name like `PriceUSD` into `price_usd`
+func ToSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) ||
+				unicode.IsUpper(prev) && nextLower {
+				b.WriteRune('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// nolint: gochecknoglobals
+var defaultMapper = &Mapper{}
+
+// Mapper maps the columns returned by a query to the fields of a struct tagged
+// with `db`. The fields of each struct type are looked up once and cached,
+// so a Mapper must not be changed once it's used
+type Mapper struct {
+	// Lenient discards columns without a matching field instead of failing
+	Lenient bool
+	// NameMapper names the columns of fields without a `db` tag,
+	// it defaults to ToSnakeCase
+	NameMapper func(string) string
+	fields     sync.Map
+}
+
+func (m *Mapper) mapName(name string) string {
+	if m.NameMapper == nil {
+		return ToSnakeCase(name)
+	}
+	return m.NameMapper(name)
+}
+
+// UnmappedColumnsError is returned when scanning columns that have no matching
+// field on the destination struct
+type UnmappedColumnsError struct {
+	Type    reflect.Type
+	Columns []string
+	Fields  []string
+}
+
+func (e *UnmappedColumnsError) Error() string {
+	return fmt.Sprintf("columns %s have no matching db field in %s, its fields are %s",
+		strings.Join(e.Columns, ", "), e.Type, strings.Join(e.Fields, ", "))
+}
+
+// fieldMap returns the field indexes of `t` by column
+func (m *Mapper) fieldMap(t reflect.Type) map[string][]int {
+	if fm, ok := m.fields.Load(t); ok {
+		return fm.(map[string][]int)
+	}
+	fm := make(map[string][]int)
+	for _, f := range m.dominantFields(t) {
+		fm[f.column] = f.index
+	}
+	actual, _ := m.fields.LoadOrStore(t, fm)
+	return actual.(map[string][]int)
+}
+
+// scanner returns a rowScanner scanning `columns` into values of type `t`
+func (m *Mapper) scanner(t reflect.Type, columns []string) (*rowScanner, error) {
+	rs := &rowScanner{scannable: isScannable(t)}
+	if rs.scannable {
+		return rs, nil
+	}
+
+	fm := m.fieldMap(t)
+	rs.indexes = make([][]int, len(columns))
+	rs.values = make([]interface{}, len(columns))
+	var unmapped []string
+	for i, c := range columns {
+		index, ok := fm[c]
+		if !ok && !m.Lenient {
+			unmapped = append(unmapped, c)
+		}
+		rs.indexes[i] = index
+	}
+	if len(unmapped) > 0 {
+		fields := make([]string, 0, len(fm))
+		for c := range fm {
+			fields = append(fields, c)
+		}
+		sort.Strings(fields)
+		return nil, &UnmappedColumnsError{Type: t, Columns: unmapped, Fields: fields}
+	}
+	return rs, nil
+}
+
+// discard is a scan target ignoring the value of a column
+type discard struct{}
+
+// Scan ignores `value`
+func (discard) Scan(interface{}) error {
+	return nil
+}
+
+// rowScanner scans the rows of a query into values of a type
+type rowScanner struct {
+	scannable bool
+	indexes   [][]int
+	values    []interface{}
+}
+
+// scan scans the current row of `rows` into the addressable value `v`
+func (rs *rowScanner) scan(rows RowSource, v reflect.Value) error {
+	if rs.scannable {
+		return rows.Scan(v.Addr().Interface())
+	}
+	for i, index := range rs.indexes {
+		if index == nil {
+			rs.values[i] = discard{}
+			continue
+		}
+		rs.values[i] = fieldByIndex(v, index).Addr().Interface()
+	}
+	return rows.Scan(rs.values...)
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type Audit struct {
+	CreatedAt time.Time
+	UpdatedBy string `db:"updated_by"`
+}
+
+type party struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+type trade struct {
+	*Audit
+	ID       int64 `db:"id"`
+	PriceUSD float64
+	Buyer    party  `db:"buyer,inline"`
+	Seller   *party `db:"seller,inline"`
+	Ignored  string `db:"-"`
+	internal string
+}
+
+func TestToSnakeCase(t *testing.T) {
+	testCases := map[string]string{
+		"ID":         "id",
+		"PriceUSD":   "price_usd",
+		"HTTPServer": "http_server",
+		"Region2Id":  "region2_id",
+		"createdAt":  "created_at",
+	}
+	for input, expected := range testCases {
+		require.Equal(t, expected, ToSnakeCase(input))
+	}
+}
+
+func TestStructFields(t *testing.T) {
+	m := &Mapper{}
+	fm := m.fieldMap(reflect.TypeOf(trade{}))
+	require.Equal(t, map[string][]int{
+		"created_at":  {0, 0},
+		"updated_by":  {0, 1},
+		"id":          {1},
+		"price_usd":   {2},
+		"buyer.id":    {3, 0},
+		"buyer.name":  {3, 1},
+		"seller.id":   {4, 0},
+		"seller.name": {4, 1},
+	}, fm)
+
+	var tr trade
+	v := reflect.ValueOf(&tr).Elem()
+	fieldByIndex(v, fm["seller.name"]).SetString("acme")
+	fieldByIndex(v, fm["updated_by"]).SetString("bot")
+	require.Equal(t, "acme", tr.Seller.Name)
+	require.Equal(t, "bot", tr.UpdatedBy)
+}
+
+// sliceRows is a RowSource returning `values` for `columns`
+type sliceRows struct {
+	columns []string
+	values  [][]interface{}
+	i       int
+}
+
+func (r *sliceRows) Columns() ([]string, error) {
+	return r.columns, nil
+}
+
+func (r *sliceRows) Next() bool {
+	r.i++
+	return r.i <= len(r.values)
+}
+
+func (r *sliceRows) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		if s, ok := d.(interface{ Scan(interface{}) error }); ok {
+			if err := s.Scan(r.values[r.i-1][i]); err != nil {
+				return err
+			}
+			continue
+		}
+		reflect.ValueOf(d).Elem().Set(reflect.ValueOf(r.values[r.i-1][i]))
+	}
+	return nil
+}
+
+func (r *sliceRows) Err() error {
+	return nil
+}
+
+func (r *sliceRows) Close() error {
+	return nil
+}
+
+func TestScanAllUnmapped(t *testing.T) {
+	rows := func() *sliceRows {
+		return &sliceRows{
+			columns: []string{"id", "region", "name", "currency"},
+			values:  [][]interface{}{{int64(1), "eu", "acme", "usd"}},
+		}
+	}
+
+	var parties []party
+	err := ScanAll(rows(), &parties)
+	var unmapped *UnmappedColumnsError
+	require.ErrorAs(t, err, &unmapped)
+	require.Equal(t, []string{"region", "currency"}, unmapped.Columns)
+	require.Equal(t, []string{"id", "name"}, unmapped.Fields)
+	require.EqualError(t, err, "columns region, currency have no matching db field "+
+		"in db.party, its fields are id, name")
+
+	lenient := &Mapper{Lenient: true}
+	require.NoError(t, scanAll(lenient, rows(), &parties, false))
+	require.Equal(t, []party{{ID: 1, Name: "acme"}}, parties)
+}
+
+type base struct {
+	ID    int64 `db:"id"`
+	Label string
+}
+
+type shadowing struct {
+	base
+	ID    int64 `db:"id"`
+	Name  string
+	Label string `db:"name"`
+}
+
+func TestDominantFields(t *testing.T) {
+	m := &Mapper{}
+	require.Equal(t, map[string][]int{
+		"id":    {1},
+		"label": {0, 1},
+		"name":  {3},
+	}, m.fieldMap(reflect.TypeOf(shadowing{})))
+
+	query, args, err := Named("SELECT :id", shadowing{base: base{ID: 1}, ID: 2})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT $1", query)
+	require.Equal(t, []interface{}{int64(2)}, args)
+}
@@ -0,0 +1,232 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/agflow/tools/log"
+)
+
+// QueryStats describes a query run by an Instrumented service
+type QueryStats struct {
+	// Name is the name given by QueryName, or else the name of the query in
+	// Instrumented.Names, if it's there
+	Name     string
+	Query    string
+	Duration time.Duration
+	// Rows is the number of rows returned or written by the query
+	Rows int64
+	Err  error
+}
+
+// MetricsSink receives the stats of every query run by an Instrumented service
+type MetricsSink interface {
+	Observe(QueryStats)
+}
+
+// Instrumented is a db.Service timing the queries of the wrapped Service.
+// Queries slower than SlowThreshold are logged, with their args redacted
+type Instrumented struct {
+	Service
+	// SlowThreshold is the duration from which queries are logged, zero disables it
+	SlowThreshold time.Duration
+	// Sink receives the stats of every query when it's set
+	Sink MetricsSink
+	// Names are the names of queries by their text, as returned by file.Names
+	Names map[string]string
+	// name names every query, set by QueryName
+	name string
+}
+
+// NewInstrumented returns `svc` instrumented with `threshold` and `sink`
+func NewInstrumented(svc Service, threshold time.Duration, sink MetricsSink) *Instrumented {
+	return &Instrumented{Service: svc, SlowThreshold: threshold, Sink: sink}
+}
+
+// QueryName returns `svc` naming its queries `name` when it's Instrumented, or `svc`
+// itself otherwise. It names the queries that can't be found by their text in
+// Instrumented.Names, like the rendered templates of file.Query
+func QueryName(svc Service, name string) Service {
+	i, ok := svc.(*Instrumented)
+	if !ok {
+		return svc
+	}
+	named := *i
+	named.name = name
+	return &named
+}
+
+// wrap instruments `svc` with the same settings as `i`
+func (i *Instrumented) wrap(svc Service) *Instrumented {
+	return &Instrumented{
+		Service:       svc,
+		SlowThreshold: i.SlowThreshold,
+		Sink:          i.Sink,
+		Names:         i.Names,
+	}
+}
+
+// observe reports a query started at `start`
+func (i *Instrumented) observe(
+	start time.Time, query string, args []interface{}, rows int64, err error,
+) {
+	name := i.name
+	if name == "" {
+		name = i.Names[query]
+	}
+	stats := QueryStats{
+		Name:     name,
+		Query:    query,
+		Duration: time.Since(start),
+		Rows:     rows,
+		Err:      err,
+	}
+	if i.SlowThreshold > 0 && stats.Duration >= i.SlowThreshold {
+		if name == "" {
+			name = "query"
+		}
+		log.Warnf("slow %s took %s: %s %s",
+			name, stats.Duration, strings.Join(strings.Fields(query), " "), redact(args))
+	}
+	if i.Sink != nil {
+		i.Sink.Observe(stats)
+	}
+}
+
+// redact describes `args` by their types only
+func redact(args []interface{}) string {
+	types := make([]string, len(args))
+	for i, arg := range args {
+		types[i] = fmt.Sprintf("$%d:%T", i+1, arg)
+	}
+	return "[" + strings.Join(types, " ") + "]"
+}
+
+// sliceLen returns the length of the slice pointed by `dest`, zero when the query
+// selecting into it failed
+func sliceLen(dest interface{}, err error) int64 {
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	if err != nil || v.Kind() != reflect.Slice {
+		return 0
+	}
+	return int64(v.Len())
+}
+
+func found(err error) int64 {
+	if err != nil {
+		return 0
+	}
+	return 1
+}
+
+// Select selects using the `query` and `args` and set the result on `dest`
+func (i *Instrumented) Select(dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := i.Service.Select(dest, query, args...)
+	i.observe(start, query, args, sliceLen(dest, err), err)
+	return err
+}
+
+// Get gets the first row using the `query` and `args` and set it on `dest`
+func (i *Instrumented) Get(dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := i.Service.Get(dest, query, args...)
+	i.observe(start, query, args, found(err), err)
+	return err
+}
+
+// GetUnique is like Get, but it fails when `query` returns more than one row
+func (i *Instrumented) GetUnique(dest interface{}, query string, args ...interface{}) error {
+	start := time.Now()
+	err := i.Service.GetUnique(dest, query, args...)
+	i.observe(start, query, args, found(err), err)
+	return err
+}
+
+// Stream runs `query` with `args` and returns its rows to be scanned one at a time.
+// The query is reported when its rows are closed
+func (i *Instrumented) Stream(
+	ctx context.Context, query string, args ...interface{},
+) (Rows, error) {
+	start := time.Now()
+	rows, err := i.Service.Stream(ctx, query, args...)
+	if err != nil {
+		i.observe(start, query, args, 0, err)
+		return nil, err
+	}
+	return &instrumentedRows{Rows: rows, i: i, start: start, query: query, args: args}, nil
+}
+
+// NamedSelect is like Select, but binds the `:name` parameters of `query` to `arg`
+func (i *Instrumented) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	start := time.Now()
+	err := i.Service.NamedSelect(dest, query, arg)
+	i.observe(start, query, []interface{}{arg}, sliceLen(dest, err), err)
+	return err
+}
+
+// Exec executes using the `query` and `args`
+func (i *Instrumented) Exec(query string, args ...interface{}) error {
+	start := time.Now()
+	err := i.Service.Exec(query, args...)
+	i.observe(start, query, args, 0, err)
+	return err
+}
+
+// NamedExec is like Exec, but binds the `:name` parameters of `query` to `arg`
+func (i *Instrumented) NamedExec(query string, arg interface{}) error {
+	start := time.Now()
+	err := i.Service.NamedExec(query, arg)
+	i.observe(start, query, []interface{}{arg}, 0, err)
+	return err
+}
+
+// BulkInsert writes `rows` into `table` and returns the number of rows written
+func (i *Instrumented) BulkInsert(
+	ctx context.Context, table string, rows interface{}, opts BulkOptions,
+) (int64, error) {
+	start := time.Now()
+	n, err := i.Service.BulkInsert(ctx, table, rows, opts)
+	i.observe(start, "BULK INSERT INTO "+table, nil, n, err)
+	return n, err
+}
+
+// WithTx runs `fn` in a transaction whose queries are instrumented as well
+func (i *Instrumented) WithTx(
+	ctx context.Context, opts *sql.TxOptions, fn func(Service) error,
+) error {
+	return i.Service.WithTx(ctx, opts, func(tx Service) error {
+		return fn(i.wrap(tx))
+	})
+}
+
+// instrumentedRows counts the rows of a query to report it when closed
+type instrumentedRows struct {
+	Rows
+	i     *Instrumented
+	start time.Time
+	query string
+	args  []interface{}
+	n     int64
+}
+
+func (r *instrumentedRows) Next() bool {
+	if r.Rows.Next() {
+		r.n++
+		return true
+	}
+	return false
+}
+
+func (r *instrumentedRows) Close() error {
+	err := r.Rows.Close()
+	if r.i != nil {
+		r.i.observe(r.start, r.query, r.args, r.n, r.Err())
+		r.i = nil
+	}
+	return err
+}
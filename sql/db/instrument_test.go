@@ -0,0 +1,136 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/log"
+	"github.com/agflow/tools/sql/db"
+	"github.com/agflow/tools/sql/file"
+)
+
+// statsSink records the stats of every query
+type statsSink struct {
+	stats []db.QueryStats
+}
+
+func (s *statsSink) Observe(stats db.QueryStats) {
+	s.stats = append(s.stats, stats)
+}
+
+// warnings records the warnings logged until the test ends
+func warnings(t *testing.T) *[]string {
+	var msgs []string
+	log.AddHook(func(info log.MetaInfo) error {
+		if info.Lvl == log.WarnLvl {
+			msgs = append(msgs, info.Msg)
+		}
+		return nil
+	})
+	t.Cleanup(func() { log.EmptyHooks(nil) })
+	return &msgs
+}
+
+func TestInstrumentedObserve(t *testing.T) {
+	fake := pricesFake()
+	sink := &statsSink{}
+	svc := db.NewInstrumented(fake, time.Hour, sink)
+	svc.Names = map[string]string{"SELECT id, price FROM prices": "Prices.GetAll"}
+	msgs := warnings(t)
+
+	var prices []pagePrice
+	require.NoError(t, svc.Select(&prices, "SELECT id, price FROM prices"))
+	require.Empty(t, *msgs)
+	require.Len(t, sink.stats, 1)
+	require.Equal(t, "Prices.GetAll", sink.stats[0].Name)
+	require.Equal(t, "SELECT id, price FROM prices", sink.stats[0].Query)
+	require.Equal(t, int64(3), sink.stats[0].Rows)
+	require.NoError(t, sink.stats[0].Err)
+
+	svc.SlowThreshold = time.Nanosecond
+	require.NoError(t, svc.Exec("UPDATE prices\n  SET price = $1 WHERE id = $2", 42.25, int64(1)))
+	require.Len(t, *msgs, 1)
+	require.Regexp(t, `^slow query took .+: UPDATE prices SET price = \$1 WHERE id = \$2 `+
+		`\[\$1:float64 \$2:int64\]$`, (*msgs)[0])
+	require.NotContains(t, (*msgs)[0], "42.25")
+
+	require.NoError(t, svc.Select(&prices, "SELECT id, price FROM prices"))
+	require.Regexp(t, `^slow Prices.GetAll took `, (*msgs)[1])
+
+	failure := errors.New("failure")
+	fake.Expect("SELECT broken").WillReturnError(failure)
+	require.Equal(t, failure, svc.Select(&prices, "SELECT broken"))
+	last := sink.stats[len(sink.stats)-1]
+	require.Equal(t, failure, last.Err)
+	require.Zero(t, last.Rows)
+}
+
+func TestInstrumentedStream(t *testing.T) {
+	sink := &statsSink{}
+	svc := db.NewInstrumented(pricesFake(), 0, sink)
+
+	rows, err := svc.Stream(context.Background(), "SELECT id, price FROM prices")
+	require.NoError(t, err)
+	require.True(t, rows.Next())
+	require.True(t, rows.Next())
+	require.Empty(t, sink.stats)
+	require.NoError(t, rows.Close())
+	require.NoError(t, rows.Close())
+	require.Len(t, sink.stats, 1)
+	require.Equal(t, int64(2), sink.stats[0].Rows)
+
+	_, err = svc.Stream(context.Background(), "SELECT unknown")
+	require.Error(t, err)
+	require.Len(t, sink.stats, 2)
+	require.Error(t, sink.stats[1].Err)
+}
+
+func TestInstrumentedWithTx(t *testing.T) {
+	sink := &statsSink{}
+	svc := db.NewInstrumented(pricesFake(), 0, sink)
+
+	err := svc.WithTx(context.Background(), nil, func(tx db.Service) error {
+		require.IsType(t, &db.Instrumented{}, tx)
+		return tx.Exec("DELETE FROM prices")
+	})
+	require.NoError(t, err)
+	require.Len(t, sink.stats, 1)
+	require.Equal(t, "DELETE FROM prices", sink.stats[0].Query)
+}
+
+func TestInstrumentedNames(t *testing.T) {
+	queriesFS := fstest.MapFS{
+		"sql/get.sql":  {Data: []byte("SELECT id, price FROM prices")},
+		"sql/list.sql": {Data: []byte("SELECT id, price FROM prices{{if .Spot}} WHERE spot{{end}}")},
+	}
+	var queries struct {
+		Get  file.Query `sql:"get"`
+		List file.Query `sql:"list"`
+	}
+	require.NoError(t, file.LoadSQLFiles("sql/", queriesFS, &queries))
+
+	fake := pricesFake()
+	fake.Expect("SELECT id, price FROM prices WHERE spot").
+		WillReturnRows([]string{"id", "price"}, []interface{}{int64(1), 1.5})
+	sink := &statsSink{}
+	svc := db.NewInstrumented(fake, 0, sink)
+	svc.Names = file.Names(&queries)
+
+	var prices []pagePrice
+	require.NoError(t, svc.Select(&prices, queries.Get.String()))
+	list, err := queries.List.Render(map[string]bool{"Spot": true})
+	require.NoError(t, err)
+	require.NoError(t, svc.Select(&prices, list))
+	require.NoError(t, db.QueryName(svc, "List").Select(&prices, list))
+	require.NoError(t, db.QueryName(fake, "List").Select(&prices, list))
+
+	require.Len(t, sink.stats, 3)
+	require.Equal(t, "Get", sink.stats[0].Name)
+	require.Empty(t, sink.stats[1].Name)
+	require.Equal(t, "List", sink.stats[2].Name)
+}
@@ -0,0 +1,31 @@
+package db_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql"
+	"github.com/agflow/tools/sql/db/dbtest"
+)
+
+func TestSelectJSON(t *testing.T) {
+	type meta struct {
+		Source string `json:"source"`
+	}
+	type contract struct {
+		ID   int64          `db:"id"`
+		Meta sql.JSON[meta] `db:"meta"`
+	}
+	fake := dbtest.New()
+	fake.Expect("SELECT id, meta FROM contracts").
+		WillReturnRows([]string{"id", "meta"},
+			[]interface{}{int64(1), []byte(`{"source":"cme"}`)}, []interface{}{int64(2), nil})
+
+	var contracts []contract
+	require.NoError(t, fake.Select(&contracts, "SELECT id, meta FROM contracts"))
+	require.Equal(t, []contract{
+		{ID: 1, Meta: sql.NewJSON(meta{Source: "cme"})},
+		{ID: 2},
+	}, contracts)
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+
+	"github.com/agflow/tools/cache"
+	"github.com/agflow/tools/log"
+)
+
+// listenerPingInterval is how often the connection of a Listener is checked
+const listenerPingInterval = time.Minute
+
+// Handler handles the payload of a notification
+type Handler func(ctx context.Context, payload []byte) error
+
+// JSONHandler returns a Handler decoding JSON payloads into T before calling `fn`
+func JSONHandler[T any](fn func(context.Context, T) error) Handler {
+	return func(ctx context.Context, payload []byte) error {
+		var v T
+		if err := json.Unmarshal(payload, &v); err != nil {
+			return err
+		}
+		return fn(ctx, v)
+	}
+}
+
+// InvalidateHandler returns a Handler deleting from `c` the keys returned by `keys`
+// for the JSON payload of each notification
+func InvalidateHandler[T any](c cache.Service, keys func(T) []string) Handler {
+	return JSONHandler(func(ctx context.Context, v T) error {
+		if k := keys(v); len(k) > 0 {
+			return c.Delete(ctx, k...)
+		}
+		return nil
+	})
+}
+
+// Listener delivers the notifications of postgres channels to their handlers.
+// Its connection is re-established with an exponential backoff when lost
+type Listener struct {
+	listener *pq.Listener
+	mu       sync.RWMutex
+	handlers map[string][]Handler
+}
+
+// NewListener returns a Listener connected to `url`, waiting between `minBackoff`
+// and `maxBackoff` before reconnecting
+func NewListener(url string, minBackoff, maxBackoff time.Duration) *Listener {
+	l := &Listener{handlers: make(map[string][]Handler)}
+	l.listener = pq.NewListener(url, minBackoff, maxBackoff, logListenerEvent)
+	return l
+}
+
+func logListenerEvent(event pq.ListenerEventType, err error) {
+	switch event {
+	case pq.ListenerEventDisconnected:
+		log.Warnf("listener disconnected: %v", err)
+	case pq.ListenerEventConnectionAttemptFailed:
+		log.Warnf("listener can't reconnect: %v", err)
+	case pq.ListenerEventReconnected:
+		log.Info("listener reconnected, notifications may have been missed")
+	}
+}
+
+// Handle listens to `channel` and delivers its notifications to `h`
+func (l *Listener) Handle(channel string, h Handler) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.handlers[channel]; !ok {
+		if err := l.listener.Listen(channel); err != nil {
+			return err
+		}
+	}
+	l.handlers[channel] = append(l.handlers[channel], h)
+	return nil
+}
+
+// Run delivers notifications until `ctx` is done or the listener is closed
+func (l *Listener) Run(ctx context.Context) error {
+	ticker := time.NewTicker(listenerPingInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n, ok := <-l.listener.Notify:
+			if !ok {
+				return nil
+			}
+			// a nil notification is sent after reconnecting
+			if n != nil {
+				l.dispatch(ctx, n)
+			}
+		case <-ticker.C:
+			go func() { log.ErrorType(l.listener.Ping()) }()
+		}
+	}
+}
+
+func (l *Listener) dispatch(ctx context.Context, n *pq.Notification) {
+	l.mu.RLock()
+	handlers := l.handlers[n.Channel]
+	l.mu.RUnlock()
+	for _, h := range handlers {
+		if err := h(ctx, []byte(n.Extra)); err != nil {
+			log.Errorf("can't handle notification on %q: %v", n.Channel, err)
+		}
+	}
+}
+
+// Close stops listening and closes the connection
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
@@ -0,0 +1,59 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql/db"
+)
+
+// fakeCache records the keys deleted from it
+type fakeCache struct {
+	deleted []string
+}
+
+func (c *fakeCache) Get(context.Context, string) ([]byte, error) {
+	return nil, nil
+}
+
+func (c *fakeCache) Set(context.Context, string, interface{}, time.Duration) error {
+	return nil
+}
+
+func (c *fakeCache) Delete(_ context.Context, keys ...string) error {
+	c.deleted = append(c.deleted, keys...)
+	return nil
+}
+
+type priceChange struct {
+	ID     int64  `json:"id"`
+	Region string `json:"region"`
+}
+
+func TestJSONHandler(t *testing.T) {
+	var got priceChange
+	h := db.JSONHandler(func(_ context.Context, change priceChange) error {
+		got = change
+		return nil
+	})
+	require.NoError(t, h(context.Background(), []byte(`{"id":1,"region":"eu"}`)))
+	require.Equal(t, priceChange{ID: 1, Region: "eu"}, got)
+	require.Error(t, h(context.Background(), []byte(`{"id":`)))
+}
+
+func TestInvalidateHandler(t *testing.T) {
+	c := &fakeCache{}
+	h := db.InvalidateHandler(c, func(change priceChange) []string {
+		if change.Region == "" {
+			return nil
+		}
+		return []string{"prices:" + change.Region, "regions"}
+	})
+	require.NoError(t, h(context.Background(), []byte(`{"id":1,"region":"eu"}`)))
+	require.NoError(t, h(context.Background(), []byte(`{"id":2}`)))
+	require.Equal(t, []string{"prices:eu", "regions"}, c.deleted)
+	require.Error(t, h(context.Background(), []byte(`not json`)))
+}
@@ -0,0 +1,184 @@
+package db
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx/reflectx"
+
+	"github.com/agflow/tools/sql/internal/lexer"
+)
+
+// Named binds the `:name` parameters of `query` to `arg`, which is a struct
+// mapped by `db` tags or a map[string]interface{}, and returns the query
+// with postgres `$n` placeholders and its arguments. Slices are expanded
+// into a list of placeholders so that they can be used in `IN (:ids)`
+func Named(query string, arg interface{}) (string, []interface{}, error) {
+	return defaultMapper.named(query, arg)
+}
+
+func (m *Mapper) named(query string, arg interface{}) (string, []interface{}, error) {
+	lookup, err := m.namedLookup(arg)
+	if err != nil {
+		return "", nil, err
+	}
+	return bindNamed(query, arg, lookup)
+}
+
+// sqlxNamed binds `query` like Named, looking up the fields of `arg` with the sqlx
+// mapper `m`, so that parameters are named like the columns scanned by sqlx
+func sqlxNamed(
+	m *reflectx.Mapper, query string, arg interface{},
+) (string, []interface{}, error) {
+	if values, ok := arg.(map[string]interface{}); ok {
+		return bindNamed(query, arg, mapLookup(values))
+	}
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Struct {
+		return "", nil, fmt.Errorf("expected a struct or a map but got %T", arg)
+	}
+	names := m.TypeMap(v.Type()).Names
+	return bindNamed(query, arg, func(name string) (interface{}, bool) {
+		fi, ok := names[name]
+		if !ok {
+			return nil, false
+		}
+		return structValue(v, fi.Index)
+	})
+}
+
+// lookupFunc looks up the value bound to a named parameter
+type lookupFunc func(name string) (interface{}, bool)
+
+// bindNamed replaces the `:name` parameters of `query` by the placeholders of
+// the values returned by `lookup`
+func bindNamed(
+	query string, arg interface{}, lookup lookupFunc,
+) (string, []interface{}, error) {
+	var (
+		b    strings.Builder
+		args []interface{}
+		err  error
+	)
+	for i := 0; i < len(query); {
+		if n := skipped(query, i); n > 0 {
+			b.WriteString(query[i : i+n])
+			i += n
+			continue
+		}
+		name := paramName(query, i)
+		if name == "" {
+			b.WriteByte(query[i])
+			i++
+			continue
+		}
+		v, ok := lookup(name)
+		if !ok {
+			return "", nil, fmt.Errorf("could not find name %q in %T", name, arg)
+		}
+		if args, err = bindValue(&b, args, name, v); err != nil {
+			return "", nil, err
+		}
+		i += len(name) + 1
+	}
+	return b.String(), args, nil
+}
+
+// skipped returns the length of the quoted text, comment or cast starting at `i`
+// of `query`, which are kept as they are
+func skipped(query string, i int) int {
+	if strings.HasPrefix(query[i:], "::") {
+		return 2
+	}
+	return lexer.Skip(query, i)
+}
+
+// paramName returns the name of the `:name` parameter starting at `i` of `query`.
+// Names start with a letter or `_`, so that array slices like `a[1:2]` are kept
+func paramName(query string, i int) string {
+	if query[i] != ':' || i+1 == len(query) || !lexer.IsIdentByte(query[i+1], false) {
+		return ""
+	}
+	end := i + 1
+	for end < len(query) && isNameByte(query[end]) {
+		end++
+	}
+	return query[i+1 : end]
+}
+
+// namedLookup returns a function looking up the values of `arg` by name
+func (m *Mapper) namedLookup(arg interface{}) (lookupFunc, error) {
+	if values, ok := arg.(map[string]interface{}); ok {
+		return mapLookup(values), nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(arg))
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("expected a struct or a map but got %T", arg)
+	}
+	fm := m.fieldMap(v.Type())
+	return func(name string) (interface{}, bool) {
+		index, ok := fm[name]
+		if !ok {
+			return nil, false
+		}
+		return structValue(v, index)
+	}, nil
+}
+
+func mapLookup(values map[string]interface{}) lookupFunc {
+	return func(name string) (interface{}, bool) {
+		v, ok := values[name]
+		return v, ok
+	}
+}
+
+// structValue returns the value of the field of `v` at `index`, nil when it goes
+// through a nil struct pointer
+func structValue(v reflect.Value, index []int) (interface{}, bool) {
+	if f, ok := fieldValue(v, index); ok {
+		return f.Interface(), true
+	}
+	return nil, true
+}
+
+// bindValue writes the placeholders of `v` into `b` and appends it to `args`
+func bindValue(
+	b *strings.Builder, args []interface{}, name string, v interface{},
+) ([]interface{}, error) {
+	rv := reflect.ValueOf(v)
+	if _, ok := v.(driver.Valuer); ok || !isExpandable(rv) {
+		b.WriteString("$" + strconv.Itoa(len(args)+1))
+		return append(args, v), nil
+	}
+
+	if rv.Len() == 0 {
+		return nil, fmt.Errorf("empty slice passed to :%s", name)
+	}
+	for j := 0; j < rv.Len(); j++ {
+		if j > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString("$" + strconv.Itoa(len(args)+1))
+		args = append(args, rv.Index(j).Interface())
+	}
+	return args, nil
+}
+
+// isExpandable checks if `v` is a list of values, []byte is a single value
+func isExpandable(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		return v.Type().Elem().Kind() != reflect.Uint8
+	default:
+		return false
+	}
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c == '.' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		c >= '0' && c <= '9'
+}
@@ -0,0 +1,58 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmoiron/sqlx/reflectx"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamed(t *testing.T) {
+	arg := struct {
+		Region string  `db:"region"`
+		IDs    []int64 `db:"ids"`
+		Raw    []byte  `db:"raw"`
+	}{Region: "br", IDs: []int64{1, 2, 3}, Raw: []byte("x")}
+
+	query, args, err := Named(
+		`SELECT id::text, ':skip' FROM prices -- :comment
+		WHERE region = :region AND id IN (:ids) AND raw = :raw`, arg)
+	require.NoError(t, err)
+	require.Equal(t, `SELECT id::text, ':skip' FROM prices -- :comment
+		WHERE region = $1 AND id IN ($2, $3, $4) AND raw = $5`, query)
+	require.Equal(t, []interface{}{"br", int64(1), int64(2), int64(3), []byte("x")}, args)
+
+	query, args, err = Named(`SELECT * FROM prices WHERE region = :region`,
+		map[string]interface{}{"region": "us"})
+	require.NoError(t, err)
+	require.Equal(t, `SELECT * FROM prices WHERE region = $1`, query)
+	require.Equal(t, []interface{}{"us"}, args)
+
+	_, _, err = Named(`SELECT * FROM prices WHERE id = :id`, map[string]interface{}{})
+	require.Error(t, err)
+
+	query, args, err = Named(`SELECT tags[1:2], $$ :body $$ /* :a /* :b */ :c */ FROM prices`+
+		` WHERE region = :region`, map[string]interface{}{"region": "us"})
+	require.NoError(t, err)
+	require.Equal(t, `SELECT tags[1:2], $$ :body $$ /* :a /* :b */ :c */ FROM prices`+
+		` WHERE region = $1`, query)
+	require.Equal(t, []interface{}{"us"}, args)
+}
+
+func TestSQLXNamed(t *testing.T) {
+	arg := struct {
+		Region   string `db:"region"`
+		PriceUSD float64
+	}{Region: "br", PriceUSD: 10.5}
+
+	m := reflectx.NewMapperFunc("db", strings.ToLower)
+	query, args, err := sqlxNamed(m, "SELECT * FROM prices WHERE region = :region "+
+		"AND priceusd = :priceusd", arg)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM prices WHERE region = $1 AND priceusd = $2", query)
+	require.Equal(t, []interface{}{"br", 10.5}, args)
+
+	_, _, err = sqlxNamed(m, "SELECT :price_usd", arg)
+	require.Error(t, err)
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/agflow/tools/log"
+)
+
+// defaultRetryBackoff is the wait before retrying to connect when Options has none
+const defaultRetryBackoff = time.Second
+
+// Options configures the connection pool of a client. Zero values keep the
+// defaults of database/sql
+type Options struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout bounds each attempt to reach the database on startup
+	ConnectTimeout time.Duration
+	// StartupRetries is the number of times reaching the database is retried
+	// before giving up
+	StartupRetries int
+	// RetryBackoff is the wait before the first retry, it doubles on each retry
+	RetryBackoff time.Duration
+	// StatementTimeout aborts any statement running longer than it
+	StatementTimeout time.Duration
+}
+
+// pool is implemented by sql.DB and sqlx.DB
+type pool interface {
+	PingContext(context.Context) error
+	SetMaxOpenConns(int)
+	SetMaxIdleConns(int)
+	SetConnMaxLifetime(time.Duration)
+	SetConnMaxIdleTime(time.Duration)
+}
+
+// dataSource adds the runtime parameters of `opts` to the connection string `dsn`,
+// which can be an URL or a list of key/value pairs
+func (opts Options) dataSource(dsn string) (string, error) {
+	if opts.StatementTimeout <= 0 {
+		return dsn, nil
+	}
+	timeout := strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
+	if !strings.HasPrefix(dsn, "postgres://") && !strings.HasPrefix(dsn, "postgresql://") {
+		return dsn + " statement_timeout=" + timeout, nil
+	}
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	q.Set("statement_timeout", timeout)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// configure sets the pool limits of `opts` on `db` and waits for it to be reachable
+func (opts Options) configure(db pool) error {
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+
+	backoff := opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	for i := 0; ; i++ {
+		err := opts.ping(db)
+		if err == nil || i >= opts.StartupRetries {
+			return err
+		}
+		log.Warnf("can't reach database, retrying in %s: %v", backoff, err)
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+func (opts Options) ping(db pool) error {
+	ctx := context.Background()
+	if opts.ConnectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.ConnectTimeout)
+		defer cancel()
+	}
+	return db.PingContext(ctx)
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDataSource(t *testing.T) {
+	testCases := map[string]struct {
+		opts     Options
+		dsn      string
+		expected string
+	}{
+		"no timeout": {
+			dsn:      "postgres://user@localhost/prices",
+			expected: "postgres://user@localhost/prices",
+		},
+		"url": {
+			opts:     Options{StatementTimeout: 5 * time.Second},
+			dsn:      "postgres://user@localhost/prices?sslmode=disable",
+			expected: "postgres://user@localhost/prices?sslmode=disable&statement_timeout=5000",
+		},
+		"postgresql url": {
+			opts:     Options{StatementTimeout: time.Second},
+			dsn:      "postgresql://localhost/prices",
+			expected: "postgresql://localhost/prices?statement_timeout=1000",
+		},
+		"key/value": {
+			opts:     Options{StatementTimeout: 1500 * time.Millisecond},
+			dsn:      "host=localhost dbname=prices",
+			expected: "host=localhost dbname=prices statement_timeout=1500",
+		},
+	}
+	for name, testCase := range testCases {
+		dsn, err := testCase.opts.dataSource(testCase.dsn)
+		require.NoError(t, err, name)
+		require.Equal(t, testCase.expected, dsn, name)
+	}
+
+	_, err := Options{StatementTimeout: time.Second}.dataSource("postgres://local host:x/")
+	require.Error(t, err)
+}
+
+// fakePool fails the first `failures` pings and records its settings
+type fakePool struct {
+	failures     int
+	pings        int
+	maxOpenConns int
+	maxIdleConns int
+	lifetime     time.Duration
+	idleTime     time.Duration
+	deadline     bool
+}
+
+func (p *fakePool) PingContext(ctx context.Context) error {
+	p.pings++
+	_, p.deadline = ctx.Deadline()
+	if p.pings <= p.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (p *fakePool) SetMaxOpenConns(n int) {
+	p.maxOpenConns = n
+}
+
+func (p *fakePool) SetMaxIdleConns(n int) {
+	p.maxIdleConns = n
+}
+
+func (p *fakePool) SetConnMaxLifetime(d time.Duration) {
+	p.lifetime = d
+}
+
+func (p *fakePool) SetConnMaxIdleTime(d time.Duration) {
+	p.idleTime = d
+}
+
+func TestConfigure(t *testing.T) {
+	opts := Options{
+		MaxOpenConns:    10,
+		MaxIdleConns:    5,
+		ConnMaxLifetime: time.Hour,
+		ConnMaxIdleTime: time.Minute,
+		ConnectTimeout:  time.Second,
+		StartupRetries:  2,
+		RetryBackoff:    time.Millisecond,
+	}
+	p := &fakePool{failures: 2}
+	start := time.Now()
+	require.NoError(t, opts.configure(p))
+	require.GreaterOrEqual(t, time.Since(start), 3*time.Millisecond)
+	require.Equal(t, &fakePool{
+		failures: 2, pings: 3, maxOpenConns: 10, maxIdleConns: 5,
+		lifetime: time.Hour, idleTime: time.Minute, deadline: true,
+	}, p)
+
+	p = &fakePool{failures: 3}
+	require.EqualError(t, opts.configure(p), "connection refused")
+	require.Equal(t, 3, p.pings)
+
+	p = &fakePool{failures: 1}
+	require.Error(t, Options{}.configure(p))
+	require.Equal(t, 1, p.pings)
+	require.False(t, p.deadline)
+	require.Zero(t, p.maxOpenConns)
+}
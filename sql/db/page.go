@@ -0,0 +1,222 @@
+package db
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/agflow/tools/typing"
+)
+
+// Keyset paginates a query by the values of its sort columns, so that pages
+// don't shift when rows are inserted
+type Keyset struct {
+	// Columns are the sort columns of the query, together they must identify a row.
+	// They must be fields tagged with `db`, as untagged fields are named differently
+	// by each client
+	Columns []string
+	// Desc sorts the rows in descending order
+	Desc bool
+	// Limit is the number of rows of a page
+	Limit int
+	// Cursor is Page.Next of the previous page, it's empty for the first page
+	Cursor string
+	// WithTotal counts the rows of the query on Page.Total
+	WithTotal bool
+}
+
+// Offset paginates a query by skipping the rows of the previous pages
+type Offset struct {
+	// OrderBy are the expressions, like `price DESC`, sorting the rows
+	OrderBy []string
+	// Page is the number of the page, starting at 1
+	Page int
+	// Size is the number of rows of a page
+	Size int
+	// WithTotal counts the rows of the query on Page.Total
+	WithTotal bool
+}
+
+// Page describes a page of rows
+type Page struct {
+	// HasMore is set when there are rows after the page
+	HasMore bool
+	// Next is the cursor of the next page, set by SelectKeyset when HasMore is set
+	Next string
+	// Total is the number of rows of the query, if it was asked for
+	Total int64
+}
+
+// EncodeCursor encodes `values` into an opaque cursor token
+func EncodeCursor(values []interface{}) (string, error) {
+	b, err := json.Marshal(values)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// DecodeCursor decodes the values of a cursor token made by EncodeCursor.
+// Numbers are decoded as json.Number so that they keep their precision
+func DecodeCursor(cursor string) ([]interface{}, error) {
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var values []interface{}
+	if err := d.Decode(&values); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return values, nil
+}
+
+// SelectKeyset selects into `dest`, a pointer to a slice of structs, the page of
+// `query` following `k.Cursor`. The returned Page.Next holds the cursor of the
+// next page, made from the sort columns of the last row
+func SelectKeyset(
+	svc Service, dest interface{}, k Keyset, query string, args ...interface{},
+) (Page, error) {
+	if len(k.Columns) == 0 || k.Limit <= 0 {
+		return Page{}, errors.New("keyset pagination needs columns and a limit")
+	}
+	page, err := total(svc, k.WithTotal, query, args)
+	if err != nil {
+		return page, err
+	}
+
+	cursor, pageArgs, err := keysetCursor(k, args)
+	if err != nil {
+		return page, err
+	}
+	direction := ""
+	if k.Desc {
+		direction = " DESC"
+	}
+	var q strings.Builder
+	fmt.Fprintf(&q, "SELECT * FROM (%s) AS page%s", query, cursor)
+	orderBy := make([]string, len(k.Columns))
+	for i, c := range k.Columns {
+		orderBy[i] = c + direction
+	}
+	fmt.Fprintf(&q, " ORDER BY %s LIMIT %d", strings.Join(orderBy, ", "), k.Limit+1)
+
+	if err := svc.Select(dest, q.String(), pageArgs...); err != nil {
+		return page, err
+	}
+	last, ok := trim(dest, k.Limit)
+	if !ok {
+		return page, nil
+	}
+	page.HasMore = true
+	values, err := columnValues(last, k.Columns)
+	if err != nil {
+		return page, err
+	}
+	page.Next, err = EncodeCursor(values)
+	return page, err
+}
+
+// keysetCursor returns the WHERE clause selecting the rows after `k.Cursor`, with
+// `args` followed by the values of the cursor
+func keysetCursor(k Keyset, args []interface{}) (string, []interface{}, error) {
+	if k.Cursor == "" {
+		return "", args, nil
+	}
+	values, err := DecodeCursor(k.Cursor)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(values) != len(k.Columns) {
+		return "", nil, errors.New("cursor doesn't match the sort columns")
+	}
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = fmt.Sprintf("$%d", len(args)+i+1)
+	}
+	op := ">"
+	if k.Desc {
+		op = "<"
+	}
+	where := fmt.Sprintf(" WHERE (%s) %s (%s)",
+		strings.Join(k.Columns, ", "), op, strings.Join(placeholders, ", "))
+	return where, append(append([]interface{}(nil), args...), values...), nil
+}
+
+// SelectOffset selects into `dest`, a pointer to a slice, the page `o.Page` of `query`
+func SelectOffset(
+	svc Service, dest interface{}, o Offset, query string, args ...interface{},
+) (Page, error) {
+	if o.Page < 1 || o.Size <= 0 {
+		return Page{}, errors.New("offset pagination needs a page and a size")
+	}
+	page, err := total(svc, o.WithTotal, query, args)
+	if err != nil {
+		return page, err
+	}
+
+	var q strings.Builder
+	fmt.Fprintf(&q, "SELECT * FROM (%s) AS page", query)
+	if len(o.OrderBy) > 0 {
+		q.WriteString(" ORDER BY " + strings.Join(o.OrderBy, ", "))
+	}
+	fmt.Fprintf(&q, " LIMIT %d OFFSET %d", o.Size+1, (o.Page-1)*o.Size)
+
+	if err := svc.Select(dest, q.String(), args...); err != nil {
+		return page, err
+	}
+	_, page.HasMore = trim(dest, o.Size)
+	return page, nil
+}
+
+// total counts the rows of `query` when `count` is set
+func total(svc Service, count bool, query string, args []interface{}) (Page, error) {
+	var page Page
+	if !count {
+		return page, nil
+	}
+	err := svc.Get(&page.Total, fmt.Sprintf("SELECT count(*) FROM (%s) AS total", query), args...)
+	return page, err
+}
+
+// trim cuts the slice pointed by `dest` to `limit` elements. It returns the last
+// element kept when there were more
+func trim(dest interface{}, limit int) (reflect.Value, bool) {
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	if v.Len() <= limit {
+		return reflect.Value{}, false
+	}
+	v.Set(v.Slice(0, limit))
+	return v.Index(limit - 1), true
+}
+
+// columnValues returns the values of the fields of `row` tagged with `columns`
+func columnValues(row reflect.Value, columns []string) ([]interface{}, error) {
+	row = reflect.Indirect(row)
+	if _, err := typing.Base(row.Type(), reflect.Struct); err != nil {
+		return nil, err
+	}
+	tagged := make(map[string][]int)
+	for _, f := range defaultMapper.dominantFields(row.Type()) {
+		if f.tagged {
+			tagged[f.column] = f.index
+		}
+	}
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		index, ok := tagged[c]
+		if !ok {
+			return nil, fmt.Errorf("sort column %q has no field tagged with `db` in %s",
+				c, row.Type())
+		}
+		if f, ok := fieldValue(row, index); ok {
+			values[i] = f.Interface()
+		}
+	}
+	return values, nil
+}
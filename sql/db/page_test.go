@@ -0,0 +1,79 @@
+package db_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql/db"
+	"github.com/agflow/tools/sql/db/dbtest"
+)
+
+type pagePrice struct {
+	ID    int64   `db:"id"`
+	Price float64 `db:"price"`
+}
+
+func TestSelectKeyset(t *testing.T) {
+	fake := dbtest.New()
+	fake.Expect("SELECT * FROM (SELECT id, price FROM prices) AS page "+
+		"WHERE (id) > ($1) ORDER BY id LIMIT 3").
+		WillReturnRows([]string{"id", "price"},
+			[]interface{}{int64(3), 1.5}, []interface{}{int64(4), 2.5}, []interface{}{int64(5), 3.5})
+
+	cursor, err := db.EncodeCursor([]interface{}{2})
+	require.NoError(t, err)
+
+	var prices []pagePrice
+	page, err := db.SelectKeyset(fake, &prices,
+		db.Keyset{Columns: []string{"id"}, Limit: 2, Cursor: cursor},
+		"SELECT id, price FROM prices")
+	require.NoError(t, err)
+	require.Equal(t, []pagePrice{{3, 1.5}, {4, 2.5}}, prices)
+	require.True(t, page.HasMore)
+
+	values, err := db.DecodeCursor(page.Next)
+	require.NoError(t, err)
+	require.Equal(t, "4", values[0].(fmt.Stringer).String())
+}
+
+func TestSelectOffset(t *testing.T) {
+	fake := dbtest.New()
+	fake.Expect("SELECT count(*) FROM (SELECT id, price FROM prices) AS total").
+		WillReturnRows([]string{"count"}, []interface{}{int64(5)})
+	fake.Expect("SELECT * FROM (SELECT id, price FROM prices) AS page "+
+		"ORDER BY price DESC LIMIT 3 OFFSET 2").
+		WillReturnRows([]string{"id", "price"},
+			[]interface{}{int64(3), 3.5}, []interface{}{int64(4), 2.5}, []interface{}{int64(5), 1.5})
+
+	var prices []pagePrice
+	page, err := db.SelectOffset(fake, &prices,
+		db.Offset{OrderBy: []string{"price DESC"}, Page: 2, Size: 2, WithTotal: true},
+		"SELECT id, price FROM prices")
+	require.NoError(t, err)
+	require.Equal(t, []pagePrice{{3, 3.5}, {4, 2.5}}, prices)
+	require.Equal(t, db.Page{HasMore: true, Total: 5}, page)
+
+	_, err = db.SelectOffset(fake, &prices, db.Offset{Size: 2}, "SELECT id, price FROM prices")
+	require.Error(t, err)
+}
+
+type untaggedPrice struct {
+	ID       int64 `db:"id"`
+	PriceUSD float64
+}
+
+func TestSelectKeysetUntaggedColumn(t *testing.T) {
+	fake := dbtest.New()
+	fake.Expect("SELECT * FROM (SELECT id, price_usd FROM prices) AS page "+
+		"ORDER BY price_usd LIMIT 2").
+		WillReturnRows([]string{"id", "price_usd"},
+			[]interface{}{int64(1), 1.5}, []interface{}{int64(2), 2.5})
+
+	var prices []untaggedPrice
+	_, err := db.SelectKeyset(fake, &prices, db.Keyset{Columns: []string{"price_usd"}, Limit: 1},
+		"SELECT id, price_usd FROM prices")
+	require.EqualError(t, err,
+		"sort column \"price_usd\" has no field tagged with `db` in db_test.untaggedPrice")
+}
@@ -11,18 +11,86 @@ import (
 	"github.com/agflow/tools/typing"
 )
 
+var (
+	// ErrNotFound is returned by Get when the query returns no rows
+	ErrNotFound = errors.New("no rows in result set")
+	// ErrMultipleRows is returned by GetUnique when the query returns more than one row
+	ErrMultipleRows = errors.New("more than one row in result set")
+)
+
+// RowSource is the part of sql.Rows used to scan rows
+type RowSource interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(...interface{}) error
+	Err() error
+	Close() error
+}
+
+// ScanAll scans every row of `rows` into `dest`, a pointer to a slice,
+// with the same rules as Select
+func ScanAll(rows RowSource, dest interface{}) error {
+	return scanAll(defaultMapper, rows, dest, false)
+}
+
+// ScanOne scans the first row of `rows` into `dest` with the same rules as Get,
+// or GetUnique when `unique` is set
+func ScanOne(rows RowSource, dest interface{}, unique bool) error {
+	return scanOne(defaultMapper, rows, dest, unique)
+}
+
+// queryer is implemented by sql.DB and sql.Tx
+type queryer interface {
+	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
+}
+
 // Select runs query on database with arguments and saves result on dest variable
-func Select(db *sql.DB, dest interface{}, query string, args ...interface{}) error {
-	ctx, cancel := context.WithCancel(context.Background())
+func Select(db queryer, dest interface{}, query string, args ...interface{}) error {
+	return selectContext(context.Background(), defaultMapper, db, dest, query, args...)
+}
+
+func selectContext(
+	ctx context.Context, m *Mapper, db queryer, dest interface{}, query string,
+	args ...interface{},
+) error {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	rows, err := db.QueryContext(ctx, query, args...)
 	if err != nil {
-		return err
+		return translate(err)
+	}
+	defer func() { log.IfErrorDiffNil(rows.Close()) }()
+
+	return translate(scanAll(m, rows, dest, false))
+}
+
+// Get runs query on database with arguments and saves the first row on dest variable.
+// It returns ErrNotFound when the query returns no rows
+func Get(db queryer, dest interface{}, query string, args ...interface{}) error {
+	return getContext(context.Background(), defaultMapper, db, dest, false, query, args...)
+}
+
+// GetUnique is like Get, but it returns ErrMultipleRows when the query
+// returns more than one row
+func GetUnique(db queryer, dest interface{}, query string, args ...interface{}) error {
+	return getContext(context.Background(), defaultMapper, db, dest, true, query, args...)
+}
+
+func getContext(
+	ctx context.Context, m *Mapper, db queryer, dest interface{}, unique bool,
+	query string, args ...interface{},
+) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return translate(err)
 	}
 	defer func() { log.IfErrorDiffNil(rows.Close()) }()
 
-	return scanAll(rows, dest, false)
+	return translate(scanOne(m, rows, dest, unique))
 }
 
 func scannerInterface() reflect.Type {
@@ -53,41 +121,24 @@ func isScannable(t reflect.Type) bool {
 
 	// it's not important that we use the right mapper for this particular object,
 	// we're only concerned on how many exported fields this struct has
-	return t.NumField() == 0
-}
-
-func processScan(rows *sql.Rows, isPtr bool, base reflect.Type, direct reflect.Value) error {
-	var vp reflect.Value
-	for rows.Next() {
-		vp = reflect.New(base)
-
-		if err := rows.Scan(vp.Interface()); err != nil {
-			return err
-		}
-
-		if isPtr {
-			direct.Set(reflect.Append(direct, vp))
-		} else {
-			direct.Set(reflect.Append(direct, reflect.Indirect(vp)))
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).PkgPath == "" {
+			return false
 		}
 	}
-	return rows.Err()
+	return true
 }
 
-func processNotScan(
-	rows *sql.Rows, isPtr bool, base reflect.Type, direct reflect.Value, columns []string,
+func processRows(
+	rows RowSource, isPtr bool, base reflect.Type, direct reflect.Value, rs *rowScanner,
 ) error {
 	var v, vp reflect.Value
-	values := make([]interface{}, len(columns))
 	for rows.Next() {
 		// create a new struct type (which returns PtrTo) and indirect it
 		vp = reflect.New(base)
 		v = reflect.Indirect(vp)
 
-		valuesByFields(v, values, columns)
-
-		// scan into the struct field pointers and append to our results
-		if err := rows.Scan(values...); err != nil {
+		if err := rs.scan(rows, v); err != nil {
 			return err
 		}
 
@@ -100,7 +151,7 @@ func processNotScan(
 	return rows.Err()
 }
 
-func scanAll(rows *sql.Rows, dest interface{}, structOnly bool) error {
+func scanAll(m *Mapper, rows RowSource, dest interface{}, structOnly bool) error {
 	value := reflect.ValueOf(dest)
 
 	// json.Unmarshal returns errors for these
@@ -119,7 +170,6 @@ func scanAll(rows *sql.Rows, dest interface{}, structOnly bool) error {
 
 	isPtr := slice.Elem().Kind() == reflect.Ptr
 	base := typing.DeRef(slice.Elem())
-	scannable := isScannable(base)
 
 	if structOnly {
 		return structOnlyError(base)
@@ -130,32 +180,58 @@ func scanAll(rows *sql.Rows, dest interface{}, structOnly bool) error {
 		return err
 	}
 
-	if scannable {
-		return processScan(rows, isPtr, base, direct)
+	rs, err := m.scanner(base, columns)
+	if err != nil {
+		return err
 	}
-	return processNotScan(rows, isPtr, base, direct, columns)
+	return processRows(rows, isPtr, base, direct, rs)
 }
 
-func findByDBTag(v reflect.Value, s string) (interface{}, bool) {
-	for i := 0; i < v.NumField(); i++ {
-		f := reflect.Indirect(v).Field(i)
-		dbTag, ok := v.Type().Field(i).Tag.Lookup("db")
-		if !ok && f.Kind() == reflect.Struct {
-			if value, ok := findByDBTag(f, s); ok {
-				return value, ok
-			}
-			continue
-		}
-		if dbTag == s {
-			return f.Addr().Interface(), true
-		}
+// scanOne scans the first row of `rows` into `dest`, which can point to a struct
+// or to a scannable value. If `unique` is set, more than one row is an error
+func scanOne(m *Mapper, rows RowSource, dest interface{}, unique bool) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr {
+		return errors.New("must pass a pointer, not a value, to Get destination")
+	}
+	if value.IsNil() {
+		return errors.New("nil pointer passed to Get destination")
+	}
+	direct := reflect.Indirect(value)
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
 	}
-	return nil, false
+
+	rs, err := m.scanner(direct.Type(), columns)
+	if err != nil {
+		return err
+	}
+
+	return scanFirst(rows, unique, func() error { return rs.scan(rows, direct) })
 }
 
-func valuesByFields(v reflect.Value, values []interface{}, columns []string) {
-	v = reflect.Indirect(v)
-	for i := 0; i < len(columns); i++ {
-		values[i], _ = findByDBTag(v, columns[i])
+// rowCursor is implemented by Rows, RowSource and sqlx.Rows
+type rowCursor interface {
+	Next() bool
+	Err() error
+}
+
+// scanFirst scans the first row of `rows` with `scan`. It returns ErrNotFound when
+// there are no rows and, if `unique` is set, ErrMultipleRows when there are more
+func scanFirst(rows rowCursor, unique bool, scan func() error) error {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return ErrNotFound
 	}
+	if err := scan(); err != nil {
+		return err
+	}
+	if unique && rows.Next() {
+		return ErrMultipleRows
+	}
+	return rows.Err()
 }
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+
+	"github.com/agflow/tools/log"
+)
+
+// Query runs `query` on `svc` with `args` and returns its rows scanned into T,
+// which can be a struct tagged with `db` or a scannable value
+func Query[T any](
+	ctx context.Context, svc Service, query string, args ...interface{},
+) ([]T, error) {
+	var result []T
+	err := SelectEach(ctx, svc, query, args, func(row T) error {
+		result = append(result, row)
+		return nil
+	})
+	return result, err
+}
+
+// QueryOne runs `query` on `svc` with `args` and returns its only row scanned into T.
+// It returns ErrNotFound when there are no rows and ErrMultipleRows when there
+// is more than one
+func QueryOne[T any](
+	ctx context.Context, svc Service, query string, args ...interface{},
+) (T, error) {
+	var result T
+	rows, err := svc.Stream(ctx, query, args...)
+	if err != nil {
+		return result, err
+	}
+	defer func() { log.ErrorType(rows.Close()) }()
+
+	err = scanFirst(rows, true, func() error { return rows.Scan(&result) })
+	return result, err
+}
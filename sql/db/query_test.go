@@ -0,0 +1,34 @@
+package db_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql/db"
+)
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	fake := pricesFake()
+	fake.Expect("SELECT id, price FROM prices WHERE id = $1").
+		WillReturnRows([]string{"id", "price"}, []interface{}{int64(1), 1.5})
+	fake.Expect("SELECT id, price FROM prices WHERE id = 0").
+		WillReturnRows([]string{"id", "price"})
+
+	prices, err := db.Query[pagePrice](ctx, fake, "SELECT id, price FROM prices")
+	require.NoError(t, err)
+	require.Equal(t, []pagePrice{{1, 1.5}, {2, 2.5}, {3, 3.5}}, prices)
+
+	price, err := db.QueryOne[pagePrice](ctx, fake,
+		"SELECT id, price FROM prices WHERE id = $1", 1)
+	require.NoError(t, err)
+	require.Equal(t, pagePrice{1, 1.5}, price)
+
+	_, err = db.QueryOne[pagePrice](ctx, fake, "SELECT id, price FROM prices WHERE id = 0")
+	require.ErrorIs(t, err, db.ErrNotFound)
+
+	_, err = db.QueryOne[pagePrice](ctx, fake, "SELECT id, price FROM prices")
+	require.ErrorIs(t, err, db.ErrMultipleRows)
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"sync/atomic"
+	"time"
+
+	"github.com/agflow/tools/log"
+)
+
+// replica is a read replica with its last known health
+type replica struct {
+	Service
+	unhealthy int32
+}
+
+func (r *replica) healthy() bool {
+	return atomic.LoadInt32(&r.unhealthy) == 0
+}
+
+// ReplicaClient is a db.Service sending reads to read replicas, in round-robin
+// and skipping the unhealthy ones, and everything else to the primary
+type ReplicaClient struct {
+	primary  Service
+	replicas []*replica
+	next     uint32
+}
+
+// NewReplicaClient returns a new db.ReplicaClient. Every replica is considered
+// healthy until CheckHealth says otherwise
+func NewReplicaClient(primary Service, replicas ...Service) *ReplicaClient {
+	c := &ReplicaClient{primary: primary, replicas: make([]*replica, len(replicas))}
+	for i := range replicas {
+		c.replicas[i] = &replica{Service: replicas[i]}
+	}
+	return c
+}
+
+// Primary returns the service of `svc` that sends every query to the primary,
+// or `svc` itself if it doesn't route queries to replicas. An Instrumented
+// service is seen through, and the primary is returned instrumented the same way
+func Primary(svc Service) Service {
+	switch s := svc.(type) {
+	case *ReplicaClient:
+		return s.Primary()
+	case *Instrumented:
+		primary := *s
+		primary.Service = Primary(s.Service)
+		return &primary
+	}
+	return svc
+}
+
+// Primary returns the primary, to force a read to see the latest writes
+func (c *ReplicaClient) Primary() Service {
+	return c.primary
+}
+
+// reader returns the next healthy replica, or the primary if there is none
+func (c *ReplicaClient) reader() Service {
+	n := len(c.replicas)
+	for i := 0; i < n; i++ {
+		r := c.replicas[int(atomic.AddUint32(&c.next, 1)-1)%n]
+		if r.healthy() {
+			return r.Service
+		}
+	}
+	return c.primary
+}
+
+// CheckHealth pings every replica and updates whether they receive reads
+func (c *ReplicaClient) CheckHealth(ctx context.Context) {
+	for i, r := range c.replicas {
+		var unhealthy int32
+		if err := r.Ping(ctx); err != nil {
+			log.Warnf("replica %d is unhealthy: %v", i, err)
+			unhealthy = 1
+		}
+		atomic.StoreInt32(&r.unhealthy, unhealthy)
+	}
+}
+
+// StartHealthChecks calls CheckHealth every `interval` until `ctx` is done
+func (c *ReplicaClient) StartHealthChecks(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				c.CheckHealth(ctx)
+			}
+		}
+	}()
+}
+
+// Select selects from a replica using the `query` and `args` and set the result on `dest`
+func (c *ReplicaClient) Select(dest interface{}, query string, args ...interface{}) error {
+	return c.reader().Select(dest, query, args...)
+}
+
+// Get gets the first row from a replica using the `query` and `args` and set it on `dest`
+func (c *ReplicaClient) Get(dest interface{}, query string, args ...interface{}) error {
+	return c.reader().Get(dest, query, args...)
+}
+
+// GetUnique is like Get, but it fails when `query` returns more than one row
+func (c *ReplicaClient) GetUnique(dest interface{}, query string, args ...interface{}) error {
+	return c.reader().GetUnique(dest, query, args...)
+}
+
+// Stream runs `query` with `args` on a replica and returns its rows to be scanned
+// one at a time
+func (c *ReplicaClient) Stream(
+	ctx context.Context, query string, args ...interface{},
+) (Rows, error) {
+	return c.reader().Stream(ctx, query, args...)
+}
+
+// NamedSelect is like Select, but binds the `:name` parameters of `query` to `arg`
+func (c *ReplicaClient) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	return c.reader().NamedSelect(dest, query, arg)
+}
+
+// Exec executes from the primary using the `query` and `args`
+func (c *ReplicaClient) Exec(query string, args ...interface{}) error {
+	return c.primary.Exec(query, args...)
+}
+
+// NamedExec is like Exec, but binds the `:name` parameters of `query` to `arg`
+func (c *ReplicaClient) NamedExec(query string, arg interface{}) error {
+	return c.primary.NamedExec(query, arg)
+}
+
+// BulkInsert writes `rows` into `table` of the primary
+func (c *ReplicaClient) BulkInsert(
+	ctx context.Context, table string, rows interface{}, opts BulkOptions,
+) (int64, error) {
+	return c.primary.BulkInsert(ctx, table, rows, opts)
+}
+
+// WithTx runs `fn` in a transaction of the primary
+func (c *ReplicaClient) WithTx(
+	ctx context.Context, opts *sql.TxOptions, fn func(Service) error,
+) error {
+	return c.primary.WithTx(ctx, opts, fn)
+}
+
+// Ping checks that the primary is reachable
+func (c *ReplicaClient) Ping(ctx context.Context) error {
+	return c.primary.Ping(ctx)
+}
+
+// Stats returns the connection pool statistics of the primary
+func (c *ReplicaClient) Stats() sql.DBStats {
+	return c.primary.Stats()
+}
+
+// Close closes the db connections of the primary and every replica
+func (c *ReplicaClient) Close() error {
+	err := c.primary.Close()
+	for _, r := range c.replicas {
+		if rErr := r.Close(); rErr != nil {
+			log.Error(rErr)
+		}
+	}
+	return err
+}
@@ -0,0 +1,69 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql/db"
+	"github.com/agflow/tools/sql/db/dbtest"
+)
+
+// downFake is a Fake whose database isn't reachable
+type downFake struct {
+	*dbtest.Fake
+}
+
+func (f downFake) Ping(context.Context) error {
+	return errors.New("connection refused")
+}
+
+func serverFake(id int64) *dbtest.Fake {
+	fake := dbtest.New()
+	fake.Expect("SELECT server_id()").WillReturnRows([]string{"id"}, []interface{}{id})
+	return fake
+}
+
+func serverID(t *testing.T, svc db.Service) int64 {
+	var id int64
+	require.NoError(t, svc.Get(&id, "SELECT server_id()"))
+	return id
+}
+
+func TestReplicaClient(t *testing.T) {
+	primary := serverFake(0)
+	replica1, replica2 := serverFake(1), serverFake(2)
+	c := db.NewReplicaClient(primary, replica1, downFake{replica2})
+
+	ids := make([]int64, 4)
+	for i := range ids {
+		ids[i] = serverID(t, c)
+	}
+	require.Equal(t, []int64{1, 2, 1, 2}, ids)
+
+	c.CheckHealth(context.Background())
+	require.Equal(t, int64(1), serverID(t, c))
+	require.Equal(t, int64(1), serverID(t, c))
+
+	require.NoError(t, c.Exec("DELETE FROM prices"))
+	require.Equal(t, []dbtest.Statement{{Query: "DELETE FROM prices"}}, primary.Executed())
+	require.Len(t, replica1.Executed(), 4)
+	require.Len(t, replica2.Executed(), 2)
+
+	require.Equal(t, int64(0), serverID(t, c.Primary()))
+	require.Equal(t, int64(0), serverID(t, db.Primary(c)))
+	instrumented := db.NewInstrumented(c, 0, nil)
+	require.Equal(t, int64(0), serverID(t, db.Primary(instrumented)))
+	require.IsType(t, &db.Instrumented{}, db.Primary(instrumented))
+}
+
+func TestReplicaClientFallback(t *testing.T) {
+	primary := serverFake(0)
+	c := db.NewReplicaClient(primary, downFake{serverFake(1)})
+	c.CheckHealth(context.Background())
+	require.Equal(t, int64(0), serverID(t, c))
+
+	require.Equal(t, int64(0), serverID(t, db.NewReplicaClient(primary)))
+}
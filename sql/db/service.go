@@ -1,8 +1,22 @@
 package db
 
+import (
+	"context"
+	"database/sql"
+)
+
 // Service is an interface of db.Service
 type Service interface {
 	Select(interface{}, string, ...interface{}) error
+	Get(interface{}, string, ...interface{}) error
+	GetUnique(interface{}, string, ...interface{}) error
+	Stream(context.Context, string, ...interface{}) (Rows, error)
+	Ping(context.Context) error
+	Stats() sql.DBStats
 	Close() error
 	Exec(string, ...interface{}) error
+	NamedSelect(interface{}, string, interface{}) error
+	NamedExec(string, interface{}) error
+	BulkInsert(context.Context, string, interface{}, BulkOptions) (int64, error)
+	WithTx(context.Context, *sql.TxOptions, func(Service) error) error
 }
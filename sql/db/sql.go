@@ -1,6 +1,7 @@
 package db
 
 import (
+	"context"
 	"database/sql"
 
 	"github.com/agflow/tools/log"
@@ -9,25 +10,113 @@ import (
 // Client is a wrapper of a sql.DB client
 type Client struct {
 	DB *sql.DB
+	// Mapper maps columns to struct fields, a nil Mapper fails on unmapped columns
+	Mapper *Mapper
+}
+
+func (c *Client) mapper() *Mapper {
+	if c.Mapper == nil {
+		return defaultMapper
+	}
+	return c.Mapper
 }
 
 // Select selects from `client` using the `query` and `args` and set the result on `dest`
 func (c *Client) Select(dest interface{}, query string, args ...interface{}) error {
-	return Select(c.DB, dest, query, args...)
+	return selectContext(context.Background(), c.mapper(), c.DB, dest, query, args...)
+}
+
+// Get gets the first row from `client` using the `query` and `args` and set it on `dest`
+func (c *Client) Get(dest interface{}, query string, args ...interface{}) error {
+	return getContext(context.Background(), c.mapper(), c.DB, dest, false, query, args...)
+}
+
+// GetUnique is like Get, but it fails when `query` returns more than one row
+func (c *Client) GetUnique(dest interface{}, query string, args ...interface{}) error {
+	return getContext(context.Background(), c.mapper(), c.DB, dest, true, query, args...)
+}
+
+// Stream runs `query` with `args` on `client` and returns its rows to be scanned one at a time
+func (c *Client) Stream(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return streamContext(ctx, c.mapper(), c.DB, query, args...)
 }
 
 // Exec executes from `client` using the `query` and `args`
 func (c *Client) Exec(query string, args ...interface{}) error {
 	_, err := c.DB.Exec(query, args...)
-	return err
+	return translate(err)
+}
+
+// NamedSelect is like Select, but binds the `:name` parameters of `query` to `arg`
+func (c *Client) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	q, args, err := c.mapper().named(query, arg)
+	if err != nil {
+		return err
+	}
+	return c.Select(dest, q, args...)
+}
+
+// NamedExec is like Exec, but binds the `:name` parameters of `query` to `arg`
+func (c *Client) NamedExec(query string, arg interface{}) error {
+	q, args, err := c.mapper().named(query, arg)
+	if err != nil {
+		return err
+	}
+	return c.Exec(q, args...)
+}
+
+// WithTx runs `fn` in a transaction, committing it if `fn` succeeds and rolling it
+// back if `fn` fails or panics. Calling WithTx on the given service creates a savepoint
+func (c *Client) WithTx(
+	ctx context.Context, opts *sql.TxOptions, fn func(Service) error,
+) error {
+	return withTx(ctx, func() (txConn, Service, error) {
+		tx, err := c.DB.BeginTx(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tx, &Tx{ctx: ctx, db: c.DB, tx: tx, mapper: c.mapper()}, nil
+	}, fn)
+}
+
+// BulkInsert writes `rows`, a slice of structs tagged with `db`, into `table` in a
+// single transaction and returns the number of rows written
+func (c *Client) BulkInsert(
+	ctx context.Context, table string, rows interface{}, opts BulkOptions,
+) (int64, error) {
+	var n int64
+	err := c.WithTx(ctx, nil, func(tx Service) (err error) {
+		n, err = tx.BulkInsert(ctx, table, rows, opts)
+		return err
+	})
+	return n, err
 }
 
-// New return a new db.Client
+// New return a new db.Client. The database isn't reached until the first query,
+// NewWithOptions replaces it to check that it's reachable on startup
 func New(url string) (*Client, error) {
 	db, err := sql.Open("postgres", url)
 	return &Client{DB: db}, err
 }
 
+// NewWithOptions returns a new db.Client configured with `opts`, once the database
+// is reachable
+func NewWithOptions(url string, opts Options) (*Client, error) {
+	dsn, err := opts.dataSource(url)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.configure(db); err != nil {
+		log.ErrorType(db.Close())
+		return nil, err
+	}
+	return &Client{DB: db}, nil
+}
+
 // MustNew return a new db.Client without an error
 func MustNew(url string) *Client {
 	dbSvc, err := New(url)
@@ -37,6 +126,16 @@ func MustNew(url string) *Client {
 	return dbSvc
 }
 
+// Ping checks that the database of `client` is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	return c.DB.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics of `client`
+func (c *Client) Stats() sql.DBStats {
+	return c.DB.Stats()
+}
+
 // Close closes db connection from the client
 func (c *Client) Close() error {
 	return c.DB.Close()
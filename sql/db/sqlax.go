@@ -1,9 +1,14 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"reflect"
+
 	"github.com/jmoiron/sqlx"
 
 	"github.com/agflow/tools/log"
+	"github.com/agflow/tools/typing"
 )
 
 // SQLXClient is a wrapper of a sqlx.DB client
@@ -13,21 +18,102 @@ type SQLXClient struct {
 
 // Select selects from `client` using the `query` and `args` and set the result on `dest`
 func (c *SQLXClient) Select(dest interface{}, query string, args ...interface{}) error {
-	return c.DB.Select(dest, query, args...)
+	return translate(c.DB.Select(dest, query, args...))
+}
+
+// Get gets the first row from `client` using the `query` and `args` and set it on `dest`
+func (c *SQLXClient) Get(dest interface{}, query string, args ...interface{}) error {
+	return sqlxGet(context.Background(), c.DB, dest, false, query, args...)
+}
+
+// GetUnique is like Get, but it fails when `query` returns more than one row
+func (c *SQLXClient) GetUnique(dest interface{}, query string, args ...interface{}) error {
+	return sqlxGet(context.Background(), c.DB, dest, true, query, args...)
+}
+
+// Stream runs `query` with `args` on `client` and returns its rows to be scanned one at a time
+func (c *SQLXClient) Stream(
+	ctx context.Context, query string, args ...interface{},
+) (Rows, error) {
+	return sqlxStreamContext(ctx, c.DB, query, args...)
 }
 
 // Exec executes from `client` using the `query` and `args`
 func (c *SQLXClient) Exec(query string, args ...interface{}) error {
 	_, err := c.DB.Exec(query, args...)
-	return err
+	return translate(err)
+}
+
+// NamedSelect is like Select, but binds the `:name` parameters of `query` to `arg`
+func (c *SQLXClient) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	q, args, err := sqlxNamed(c.DB.Mapper, query, arg)
+	if err != nil {
+		return err
+	}
+	return c.Select(dest, q, args...)
+}
+
+// NamedExec is like Exec, but binds the `:name` parameters of `query` to `arg`
+func (c *SQLXClient) NamedExec(query string, arg interface{}) error {
+	q, args, err := sqlxNamed(c.DB.Mapper, query, arg)
+	if err != nil {
+		return err
+	}
+	return c.Exec(q, args...)
+}
+
+// WithTx runs `fn` in a transaction, committing it if `fn` succeeds and rolling it
+// back if `fn` fails or panics. Calling WithTx on the given service creates a savepoint
+func (c *SQLXClient) WithTx(
+	ctx context.Context, opts *sql.TxOptions, fn func(Service) error,
+) error {
+	return withTx(ctx, func() (txConn, Service, error) {
+		tx, err := c.DB.BeginTxx(ctx, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tx, &SQLXTx{ctx: ctx, db: c.DB, tx: tx}, nil
+	}, fn)
+}
+
+// BulkInsert writes `rows`, a slice of structs tagged with `db`, into `table` in a
+// single transaction and returns the number of rows written
+func (c *SQLXClient) BulkInsert(
+	ctx context.Context, table string, rows interface{}, opts BulkOptions,
+) (int64, error) {
+	var n int64
+	err := c.WithTx(ctx, nil, func(tx Service) (err error) {
+		n, err = tx.BulkInsert(ctx, table, rows, opts)
+		return err
+	})
+	return n, err
 }
 
-// NewSQLXClient return a new db.Client
+// NewSQLXClient return a new db.Client. The database is pinged once, without retries,
+// NewSQLXClientWithOptions replaces it to retry and configure the pool
 func NewSQLXClient(url string) (*SQLXClient, error) {
 	db, err := sqlx.Connect("postgres", url)
 	return &SQLXClient{DB: db}, err
 }
 
+// NewSQLXClientWithOptions returns a new db.SQLXClient configured with `opts`,
+// once the database is reachable
+func NewSQLXClientWithOptions(url string, opts Options) (*SQLXClient, error) {
+	dsn, err := opts.dataSource(url)
+	if err != nil {
+		return nil, err
+	}
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	if err := opts.configure(db); err != nil {
+		log.ErrorType(db.Close())
+		return nil, err
+	}
+	return &SQLXClient{DB: db}, nil
+}
+
 // MustNewSQLXClient return a new db.Client without an error
 func MustNewSQLXClient(url string) *SQLXClient {
 	dbSvc, err := NewSQLXClient(url)
@@ -37,7 +123,39 @@ func MustNewSQLXClient(url string) *SQLXClient {
 	return dbSvc
 }
 
+// Ping checks that the database of `client` is reachable
+func (c *SQLXClient) Ping(ctx context.Context) error {
+	return c.DB.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics of `client`
+func (c *SQLXClient) Stats() sql.DBStats {
+	return c.DB.Stats()
+}
+
 // Close closes db connection from the client
 func (c *SQLXClient) Close() error {
 	return c.DB.Close()
 }
+
+func sqlxGet(
+	ctx context.Context, q sqlx.QueryerContext, dest interface{}, unique bool,
+	query string, args ...interface{},
+) error {
+	rows, err := q.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return translate(err)
+	}
+	defer func() { log.IfErrorDiffNil(rows.Close()) }()
+
+	return translate(scanFirst(rows, unique, func() error { return scanSQLXRow(rows, dest) }))
+}
+
+// scanSQLXRow scans the current row of `rows` into `dest`, which can
+// point to a struct or to a scannable value
+func scanSQLXRow(rows *sqlx.Rows, dest interface{}) error {
+	if t := reflect.TypeOf(dest); t != nil && isScannable(typing.DeRef(t)) {
+		return rows.Scan(dest)
+	}
+	return rows.StructScan(dest)
+}
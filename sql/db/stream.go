@@ -0,0 +1,134 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/agflow/tools/log"
+)
+
+// ErrStop can be returned by the function given to SelectEach to stop
+// iterating without failing
+var ErrStop = errors.New("stop iterating rows")
+
+// Rows iterates over the rows of a query one at a time
+type Rows interface {
+	// Next prepares the next row to be scanned, it returns false when there are
+	// no more rows or an error happened
+	Next() bool
+	// Scan scans the current row into `dest`, which can point to a struct
+	// or to a scannable value
+	Scan(dest interface{}) error
+	Err() error
+	Close() error
+}
+
+// SelectEach runs `query` on `svc` with `args` and calls `fn` for each row,
+// without loading every row into memory. Iterating stops at the first error
+// returned by `fn`, which is returned unless it's ErrStop
+func SelectEach[T any](
+	ctx context.Context, svc Service, query string, args []interface{}, fn func(T) error,
+) error {
+	rows, err := svc.Stream(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { log.ErrorType(rows.Close()) }()
+
+	for rows.Next() {
+		var row T
+		if err := rows.Scan(&row); err != nil {
+			return err
+		}
+		if err := fn(row); err != nil {
+			if errors.Is(err, ErrStop) {
+				return nil
+			}
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// sqlRows scans a RowSource with the same rules as Select
+type sqlRows struct {
+	RowSource
+	mapper  *Mapper
+	columns []string
+	// scanner is kept for the last scanned type
+	scanner *rowScanner
+	typ     reflect.Type
+}
+
+// NewRows returns Rows scanning `rows` with the same rules as Select
+func NewRows(rows RowSource) (Rows, error) {
+	return newSQLRows(defaultMapper, rows)
+}
+
+func newSQLRows(m *Mapper, rows RowSource) (*sqlRows, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		log.ErrorType(rows.Close())
+		return nil, err
+	}
+	return &sqlRows{RowSource: rows, mapper: m, columns: columns}, nil
+}
+
+func (r *sqlRows) Scan(dest interface{}) error {
+	value := reflect.ValueOf(dest)
+	if value.Kind() != reflect.Ptr {
+		return errors.New("must pass a pointer, not a value, to Scan destination")
+	}
+	if value.IsNil() {
+		return errors.New("nil pointer passed to Scan destination")
+	}
+	direct := reflect.Indirect(value)
+	if r.typ != direct.Type() {
+		rs, err := r.mapper.scanner(direct.Type(), r.columns)
+		if err != nil {
+			return err
+		}
+		r.scanner, r.typ = rs, direct.Type()
+	}
+	return r.scanner.scan(r.RowSource, direct)
+}
+
+func (r *sqlRows) Err() error {
+	return translate(r.RowSource.Err())
+}
+
+func streamContext(
+	ctx context.Context, m *Mapper, db queryer, query string, args ...interface{},
+) (Rows, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	return newSQLRows(m, rows)
+}
+
+// sqlxRows scans sqlx.Rows with the same rules as sqlx.Select
+type sqlxRows struct {
+	*sqlx.Rows
+}
+
+func (r sqlxRows) Scan(dest interface{}) error {
+	return scanSQLXRow(r.Rows, dest)
+}
+
+func (r sqlxRows) Err() error {
+	return translate(r.Rows.Err())
+}
+
+func sqlxStreamContext(
+	ctx context.Context, db sqlx.QueryerContext, query string, args ...interface{},
+) (Rows, error) {
+	rows, err := db.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, translate(err)
+	}
+	return sqlxRows{Rows: rows}, nil
+}
@@ -0,0 +1,51 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql/db"
+	"github.com/agflow/tools/sql/db/dbtest"
+)
+
+func pricesFake() *dbtest.Fake {
+	fake := dbtest.New()
+	fake.Expect("SELECT id, price FROM prices").
+		WillReturnRows([]string{"id", "price"},
+			[]interface{}{int64(1), 1.5}, []interface{}{int64(2), 2.5}, []interface{}{int64(3), 3.5})
+	return fake
+}
+
+func TestSelectEach(t *testing.T) {
+	ctx := context.Background()
+	fake := pricesFake()
+
+	var prices []pagePrice
+	err := db.SelectEach(ctx, fake, "SELECT id, price FROM prices", nil,
+		func(p pagePrice) error {
+			prices = append(prices, p)
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, []pagePrice{{1, 1.5}, {2, 2.5}, {3, 3.5}}, prices)
+
+	var ids []int64
+	err = db.SelectEach(ctx, fake, "SELECT id, price FROM prices", nil,
+		func(p pagePrice) error {
+			ids = append(ids, p.ID)
+			if p.ID == 2 {
+				return db.ErrStop
+			}
+			return nil
+		})
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2}, ids)
+
+	failure := errors.New("failure")
+	err = db.SelectEach(ctx, fake, "SELECT id, price FROM prices", nil,
+		func(pagePrice) error { return failure })
+	require.ErrorIs(t, err, failure)
+}
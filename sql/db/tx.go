@@ -0,0 +1,258 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/agflow/tools/log"
+)
+
+// maxTxAttempts is the number of times a transaction is run when postgres
+// aborts it with a serialization failure
+const maxTxAttempts = 3
+
+// ErrTxClose is returned when closing a transaction handed by WithTx
+var ErrTxClose = errors.New("transactions are closed by returning from WithTx")
+
+// txConn is implemented by sql.Tx and sqlx.Tx
+type txConn interface {
+	ExecContext(context.Context, string, ...interface{}) (sql.Result, error)
+	Commit() error
+	Rollback() error
+}
+
+// withTx runs `fn` on a transaction started by `begin`. It's retried
+// when the transaction fails because of a serialization failure
+func withTx(
+	ctx context.Context,
+	begin func() (txConn, Service, error),
+	fn func(Service) error,
+) error {
+	var err error
+	for i := 0; i < maxTxAttempts; i++ {
+		err = translate(runTx(begin, fn))
+		if !errors.Is(err, ErrSerializationFailure) || ctx.Err() != nil {
+			return err
+		}
+		log.Warnf("retrying transaction after serialization failure: %v", err)
+	}
+	return err
+}
+
+func runTx(begin func() (txConn, Service, error), fn func(Service) error) error {
+	tx, svc, err := begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			log.ErrorType(tx.Rollback())
+			panic(p)
+		}
+	}()
+
+	if err := fn(svc); err != nil {
+		log.ErrorType(tx.Rollback())
+		return err
+	}
+	return tx.Commit()
+}
+
+// withSavepoint runs `fn` inside a savepoint of `tx`, rolling back to it
+// when `fn` fails
+func withSavepoint(
+	ctx context.Context, tx txConn, depth int, svc Service, fn func(Service) error,
+) error {
+	name := fmt.Sprintf("sp_%d", depth)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return translate(err)
+	}
+	rollback := func() {
+		_, err := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+		log.ErrorType(err)
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(svc); err != nil {
+		rollback()
+		return err
+	}
+	_, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return translate(err)
+}
+
+// Tx is a db.Service running every query on a sql.Tx
+type Tx struct {
+	ctx    context.Context
+	db     *sql.DB
+	tx     *sql.Tx
+	mapper *Mapper
+	depth  int
+}
+
+// Select selects from `tx` using the `query` and `args` and set the result on `dest`
+func (t *Tx) Select(dest interface{}, query string, args ...interface{}) error {
+	return selectContext(t.ctx, t.mapper, t.tx, dest, query, args...)
+}
+
+// Get gets the first row from `tx` using the `query` and `args` and set it on `dest`
+func (t *Tx) Get(dest interface{}, query string, args ...interface{}) error {
+	return getContext(t.ctx, t.mapper, t.tx, dest, false, query, args...)
+}
+
+// GetUnique is like Get, but it fails when `query` returns more than one row
+func (t *Tx) GetUnique(dest interface{}, query string, args ...interface{}) error {
+	return getContext(t.ctx, t.mapper, t.tx, dest, true, query, args...)
+}
+
+// Stream runs `query` with `args` on `tx` and returns its rows to be scanned one at a time
+func (t *Tx) Stream(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return streamContext(ctx, t.mapper, t.tx, query, args...)
+}
+
+// Exec executes from `tx` using the `query` and `args`
+func (t *Tx) Exec(query string, args ...interface{}) error {
+	_, err := t.tx.ExecContext(t.ctx, query, args...)
+	return translate(err)
+}
+
+// NamedSelect is like Select, but binds the `:name` parameters of `query` to `arg`
+func (t *Tx) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	q, args, err := t.mapper.named(query, arg)
+	if err != nil {
+		return err
+	}
+	return t.Select(dest, q, args...)
+}
+
+// NamedExec is like Exec, but binds the `:name` parameters of `query` to `arg`
+func (t *Tx) NamedExec(query string, arg interface{}) error {
+	q, args, err := t.mapper.named(query, arg)
+	if err != nil {
+		return err
+	}
+	return t.Exec(q, args...)
+}
+
+// WithTx runs `fn` inside a savepoint of the current transaction.
+// `opts` are ignored as they can only be set when the transaction begins
+func (t *Tx) WithTx(ctx context.Context, _ *sql.TxOptions, fn func(Service) error) error {
+	nested := &Tx{ctx: ctx, db: t.db, tx: t.tx, mapper: t.mapper, depth: t.depth + 1}
+	return withSavepoint(ctx, t.tx, nested.depth, nested, fn)
+}
+
+// BulkInsert writes `rows`, a slice of structs tagged with `db`, into `table`
+// and returns the number of rows written
+func (t *Tx) BulkInsert(
+	ctx context.Context, table string, rows interface{}, opts BulkOptions,
+) (int64, error) {
+	n, err := bulkInsert(ctx, t.mapper, t.tx, table, rows, opts)
+	return n, translate(err)
+}
+
+// Ping checks that the database of `tx` is reachable
+func (t *Tx) Ping(ctx context.Context) error {
+	return t.db.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics of `tx`
+func (t *Tx) Stats() sql.DBStats {
+	return t.db.Stats()
+}
+
+// Close returns ErrTxClose, the transaction ends when WithTx returns
+func (t *Tx) Close() error {
+	return ErrTxClose
+}
+
+// SQLXTx is a db.Service running every query on a sqlx.Tx
+type SQLXTx struct {
+	ctx   context.Context
+	db    *sqlx.DB
+	tx    *sqlx.Tx
+	depth int
+}
+
+// Select selects from `tx` using the `query` and `args` and set the result on `dest`
+func (t *SQLXTx) Select(dest interface{}, query string, args ...interface{}) error {
+	return translate(t.tx.SelectContext(t.ctx, dest, query, args...))
+}
+
+// Get gets the first row from `tx` using the `query` and `args` and set it on `dest`
+func (t *SQLXTx) Get(dest interface{}, query string, args ...interface{}) error {
+	return sqlxGet(t.ctx, t.tx, dest, false, query, args...)
+}
+
+// GetUnique is like Get, but it fails when `query` returns more than one row
+func (t *SQLXTx) GetUnique(dest interface{}, query string, args ...interface{}) error {
+	return sqlxGet(t.ctx, t.tx, dest, true, query, args...)
+}
+
+// Stream runs `query` with `args` on `tx` and returns its rows to be scanned one at a time
+func (t *SQLXTx) Stream(ctx context.Context, query string, args ...interface{}) (Rows, error) {
+	return sqlxStreamContext(ctx, t.tx, query, args...)
+}
+
+// Exec executes from `tx` using the `query` and `args`
+func (t *SQLXTx) Exec(query string, args ...interface{}) error {
+	_, err := t.tx.ExecContext(t.ctx, query, args...)
+	return translate(err)
+}
+
+// NamedSelect is like Select, but binds the `:name` parameters of `query` to `arg`
+func (t *SQLXTx) NamedSelect(dest interface{}, query string, arg interface{}) error {
+	q, args, err := sqlxNamed(t.tx.Mapper, query, arg)
+	if err != nil {
+		return err
+	}
+	return t.Select(dest, q, args...)
+}
+
+// NamedExec is like Exec, but binds the `:name` parameters of `query` to `arg`
+func (t *SQLXTx) NamedExec(query string, arg interface{}) error {
+	q, args, err := sqlxNamed(t.tx.Mapper, query, arg)
+	if err != nil {
+		return err
+	}
+	return t.Exec(q, args...)
+}
+
+// WithTx runs `fn` inside a savepoint of the current transaction.
+// `opts` are ignored as they can only be set when the transaction begins
+func (t *SQLXTx) WithTx(ctx context.Context, _ *sql.TxOptions, fn func(Service) error) error {
+	nested := &SQLXTx{ctx: ctx, db: t.db, tx: t.tx, depth: t.depth + 1}
+	return withSavepoint(ctx, t.tx, nested.depth, nested, fn)
+}
+
+// BulkInsert writes `rows`, a slice of structs tagged with `db`, into `table`
+// and returns the number of rows written
+func (t *SQLXTx) BulkInsert(
+	ctx context.Context, table string, rows interface{}, opts BulkOptions,
+) (int64, error) {
+	n, err := bulkInsert(ctx, defaultMapper, t.tx, table, rows, opts)
+	return n, translate(err)
+}
+
+// Ping checks that the database of `tx` is reachable
+func (t *SQLXTx) Ping(ctx context.Context) error {
+	return t.db.PingContext(ctx)
+}
+
+// Stats returns the connection pool statistics of `tx`
+func (t *SQLXTx) Stats() sql.DBStats {
+	return t.db.Stats()
+}
+
+// Close returns ErrTxClose, the transaction ends when WithTx returns
+func (t *SQLXTx) Close() error {
+	return ErrTxClose
+}
@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTx records the statements, commits and rollbacks of a transaction
+type fakeTx struct {
+	statements []string
+	commits    int
+	rollbacks  int
+	commitErr  error
+}
+
+func (tx *fakeTx) ExecContext(
+	_ context.Context, query string, _ ...interface{},
+) (sql.Result, error) {
+	tx.statements = append(tx.statements, query)
+	return nil, nil
+}
+
+func (tx *fakeTx) Commit() error {
+	tx.commits++
+	return tx.commitErr
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.rollbacks++
+	return nil
+}
+
+// beginFake returns a begin function handing out `tx`, counting the transactions begun
+func beginFake(tx *fakeTx, begun *int) func() (txConn, Service, error) {
+	return func() (txConn, Service, error) {
+		*begun++
+		return tx, nil, nil
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	serialization := &pq.Error{Code: "40001", Message: "could not serialize access"}
+	ctx := context.Background()
+
+	tx, begun := &fakeTx{}, 0
+	require.NoError(t, withTx(ctx, beginFake(tx, &begun), func(Service) error { return nil }))
+	require.Equal(t, 1, begun)
+	require.Equal(t, 1, tx.commits)
+
+	tx, begun = &fakeTx{}, 0
+	err := withTx(ctx, beginFake(tx, &begun), func(Service) error {
+		if begun < 2 {
+			return serialization
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, begun)
+	require.Equal(t, 1, tx.rollbacks)
+	require.Equal(t, 1, tx.commits)
+
+	tx, begun = &fakeTx{commitErr: serialization}, 0
+	err = withTx(ctx, beginFake(tx, &begun), func(Service) error { return nil })
+	require.ErrorIs(t, err, ErrSerializationFailure)
+	require.Equal(t, maxTxAttempts, begun)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	tx, begun = &fakeTx{}, 0
+	err = withTx(canceled, beginFake(tx, &begun), func(Service) error { return serialization })
+	require.ErrorIs(t, err, ErrSerializationFailure)
+	require.Equal(t, 1, begun)
+
+	tx, begun = &fakeTx{}, 0
+	failure := errors.New("failure")
+	err = withTx(ctx, beginFake(tx, &begun), func(Service) error { return failure })
+	require.Equal(t, failure, err)
+	require.Equal(t, 1, begun)
+	require.Equal(t, 1, tx.rollbacks)
+	require.Zero(t, tx.commits)
+
+	beginErr := errors.New("can't begin")
+	err = withTx(ctx, func() (txConn, Service, error) { return nil, nil, beginErr },
+		func(Service) error { return nil })
+	require.Equal(t, beginErr, err)
+}
+
+func TestRunTxPanic(t *testing.T) {
+	tx, begun := &fakeTx{}, 0
+	require.PanicsWithValue(t, "boom", func() {
+		_ = runTx(beginFake(tx, &begun), func(Service) error { panic("boom") })
+	})
+	require.Equal(t, 1, tx.rollbacks)
+	require.Zero(t, tx.commits)
+}
+
+func TestWithSavepoint(t *testing.T) {
+	ctx := context.Background()
+	tx := &fakeTx{}
+	err := withSavepoint(ctx, tx, 1, nil, func(Service) error {
+		return withSavepoint(ctx, tx, 2, nil, func(Service) error { return nil })
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"SAVEPOINT sp_1", "SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_2", "RELEASE SAVEPOINT sp_1",
+	}, tx.statements)
+
+	tx = &fakeTx{}
+	failure := errors.New("failure")
+	err = withSavepoint(ctx, tx, 1, nil, func(Service) error { return failure })
+	require.Equal(t, failure, err)
+	require.Equal(t, []string{"SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1"}, tx.statements)
+
+	tx = &fakeTx{}
+	require.PanicsWithValue(t, "boom", func() {
+		_ = withSavepoint(ctx, tx, 1, nil, func(Service) error { panic("boom") })
+	})
+	require.Equal(t, []string{"SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1"}, tx.statements)
+	require.Zero(t, tx.rollbacks)
+}
@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Decimal is a postgres numeric kept in its text form, so that it doesn't lose precision
+type Decimal string
+
+// Float64 returns the closest float64 to the decimal
+func (d Decimal) Float64() (float64, error) {
+	return strconv.ParseFloat(string(d), 64)
+}
+
+// Scan scans a Decimal from its text form or a number
+func (d *Decimal) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		*d = Decimal(v)
+	case string:
+		*d = Decimal(v)
+	case int64:
+		*d = Decimal(strconv.FormatInt(v, 10))
+	case float64:
+		*d = Decimal(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("can't scan %T into a decimal", value)
+	}
+	return nil
+}
+
+// Value returns the text form of the decimal
+func (d Decimal) Value() (driver.Value, error) {
+	return string(d), nil
+}
+
+// MarshalJSON marshals the decimal as a JSON number, or a string when it isn't one,
+// like NaN
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	var n json.Number
+	if err := json.Unmarshal([]byte(d), &n); err == nil && d != "" {
+		return []byte(d), nil
+	}
+	return json.Marshal(string(d))
+}
+
+// UnmarshalJSON unmarshals a decimal from a JSON number or string
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err == nil {
+		*d = Decimal(n)
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	*d = Decimal(s)
+	return nil
+}
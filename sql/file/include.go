@@ -0,0 +1,160 @@
+package file
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"text/template"
+	"text/template/parse"
+)
+
+// maxIncludeDepth limits how deep files can include each other
+const maxIncludeDepth = 10
+
+// parseTemplate parses `text`, the content of the file `f`, and the files it includes.
+// Included files are recorded as used, and a file that can't be included fails it
+func (ld *loading) parseTemplate(f, text string, depth int) (*template.Template, error) {
+	dir := path.Dir(f)
+	included := make(map[string]*template.Template)
+	include := func(name string, data interface{}) (string, error) {
+		tmpl, ok := included[includePath(dir, name)]
+		if !ok {
+			return "", fmt.Errorf("%q wasn't included when loading %q", name, f)
+		}
+		var b strings.Builder
+		err := tmpl.Execute(&b, data)
+		return b.String(), err
+	}
+	tmpl, err := template.New(f).
+		Funcs(template.FuncMap{"include": include}).
+		Option("missingkey=error").
+		Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	names, err := includes(tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if len(names) > 0 && depth >= maxIncludeDepth {
+		return nil, fmt.Errorf("too many nested includes from %q", f)
+	}
+	for _, name := range names {
+		p := includePath(dir, name)
+		if _, ok := included[p]; ok {
+			continue
+		}
+		content, err := ld.readUsed(p)
+		if err != nil {
+			return nil, fmt.Errorf("can't include %q: %w", name, err)
+		}
+		if included[p], err = ld.parseTemplate(p, content, depth+1); err != nil {
+			return nil, err
+		}
+	}
+	return tmpl, nil
+}
+
+// includePath returns the path of the file included as `name` from `dir`
+func includePath(dir, name string) string {
+	if !strings.HasSuffix(name, ".sql") {
+		name += ".sql"
+	}
+	return path.Join(dir, name)
+}
+
+// includes returns the names of the files included by the templates of `tmpl`
+func includes(tmpl *template.Template) ([]string, error) {
+	var w includeWalker
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		if err := w.walk(t.Tree.Root); err != nil {
+			return nil, err
+		}
+	}
+	return w.names, nil
+}
+
+// includeWalker collects the names passed to `include` in a template tree
+type includeWalker struct {
+	names []string
+}
+
+func (w *includeWalker) walk(node parse.Node) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		return w.walkList(n)
+	case *parse.ActionNode:
+		return w.walkPipe(n.Pipe)
+	case *parse.TemplateNode:
+		return w.walkPipe(n.Pipe)
+	case *parse.IfNode:
+		return w.walkBranch(&n.BranchNode)
+	case *parse.RangeNode:
+		return w.walkBranch(&n.BranchNode)
+	case *parse.WithNode:
+		return w.walkBranch(&n.BranchNode)
+	}
+	return nil
+}
+
+func (w *includeWalker) walkList(list *parse.ListNode) error {
+	if list == nil {
+		return nil
+	}
+	for _, node := range list.Nodes {
+		if err := w.walk(node); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *includeWalker) walkBranch(b *parse.BranchNode) error {
+	if err := w.walkPipe(b.Pipe); err != nil {
+		return err
+	}
+	if err := w.walkList(b.List); err != nil {
+		return err
+	}
+	return w.walkList(b.ElseList)
+}
+
+func (w *includeWalker) walkPipe(pipe *parse.PipeNode) error {
+	if pipe == nil {
+		return nil
+	}
+	for _, cmd := range pipe.Cmds {
+		if err := w.walkCommand(cmd); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkCommand collects the name of an `include` command, which must be a constant
+func (w *includeWalker) walkCommand(cmd *parse.CommandNode) error {
+	for _, arg := range cmd.Args {
+		if pipe, ok := arg.(*parse.PipeNode); ok {
+			if err := w.walkPipe(pipe); err != nil {
+				return err
+			}
+		}
+	}
+	if id, ok := cmd.Args[0].(*parse.IdentifierNode); !ok || id.Ident != "include" {
+		return nil
+	}
+	if len(cmd.Args) < 2 {
+		return errors.New("include without a file name")
+	}
+	name, ok := cmd.Args[1].(*parse.StringNode)
+	if !ok {
+		return fmt.Errorf("include of %s, the file name must be a constant string", cmd.Args[1])
+	}
+	w.names = append(w.names, name.Text)
+	return nil
+}
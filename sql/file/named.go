@@ -0,0 +1,186 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/agflow/tools/log"
+)
+
+// NamedQuery is a query of a file holding several queries, delimited by `-- name:` markers
+type NamedQuery struct {
+	Name string
+	// SQL is the text of the query, starting at its `-- name:` marker
+	SQL string
+}
+
+// SplitQueries splits `text` into the queries starting at each `-- name: QueryName`
+// marker. The text before the first marker is ignored
+func SplitQueries(text string) ([]NamedQuery, error) {
+	var (
+		queries []NamedQuery
+		current *strings.Builder
+		seen    = make(map[string]bool)
+	)
+	flush := func() {
+		if current != nil {
+			queries[len(queries)-1].SQL = strings.TrimSpace(current.String())
+		}
+	}
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if key, name, ok := header(line); ok && key == "name" {
+			if name == "" {
+				return nil, errors.New("query without a name")
+			}
+			if seen[name] {
+				return nil, fmt.Errorf("query %q declared twice", name)
+			}
+			seen[name] = true
+			flush()
+			queries = append(queries, NamedQuery{Name: name})
+			current = &strings.Builder{}
+		}
+		if current != nil {
+			current.WriteString(line + "\n")
+		}
+	}
+	flush()
+	return queries, scanner.Err()
+}
+
+// readNamed sets the queries of the file `f` into the matching fields of `v`, a
+// struct or a map of strings or Queries checked by namedDest
+func (ld *loading) readNamed(f string, v reflect.Value) {
+	f = path.Clean(f)
+	text, ok := ld.readFile(f)
+	if !ok {
+		return
+	}
+	queries, err := SplitQueries(text)
+	if err != nil {
+		ld.errs.Invalid = append(ld.errs.Invalid, f+": "+err.Error())
+		return
+	}
+	if v.Kind() == reflect.Map {
+		ld.readNamedMap(f, queries, v)
+		return
+	}
+	ld.readNamedFields(f, queries, v)
+}
+
+// readNamedMap sets every query of the file `f` into the map `v`
+func (ld *loading) readNamedMap(f string, queries []NamedQuery, v reflect.Value) {
+	for _, q := range queries {
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if ld.setQuery(f, q, elem) {
+			v.SetMapIndex(reflect.ValueOf(q.Name).Convert(v.Type().Key()), elem)
+		}
+	}
+}
+
+// readNamedFields sets the queries of the file `f` into the fields of the struct `v`
+// named after them, recording the missing and unused ones
+func (ld *loading) readNamedFields(f string, queries []NamedQuery, v reflect.Value) {
+	byName := make(map[string]NamedQuery, len(queries))
+	for _, q := range queries {
+		byName[q.Name] = q
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := field.Tag.Get("sql")
+		if name == "" {
+			name = field.Name
+		}
+		q, ok := byName[name]
+		if !ok {
+			ld.errs.Missing = append(ld.errs.Missing, f+"#"+name)
+			continue
+		}
+		delete(byName, name)
+		ld.setQuery(f, q, v.Field(i))
+	}
+	for name := range byName {
+		ld.errs.Unused = append(ld.errs.Unused, f+"#"+name)
+	}
+}
+
+// namedDest returns the value of `dest` that LoadNamed can set, which is a pointer
+// to a struct, or a map of strings or Queries allocated when it's pointed to and nil
+func namedDest(dest interface{}) (reflect.Value, error) {
+	v := reflect.Indirect(reflect.ValueOf(dest))
+	switch v.Kind() {
+	case reflect.Struct:
+		if !v.CanSet() {
+			return v, fmt.Errorf("expected a pointer to a struct but got %T", dest)
+		}
+	case reflect.Map:
+		t := v.Type()
+		if t.Key().Kind() != reflect.String ||
+			t.Elem().Kind() != reflect.String && t.Elem() != queryType {
+			return v, fmt.Errorf("expected a map of strings or Queries but got %T", dest)
+		}
+		if v.IsNil() {
+			if !v.CanSet() {
+				return v, fmt.Errorf("can't load queries into a nil %T", dest)
+			}
+			v.Set(reflect.MakeMap(t))
+		}
+	default:
+		return v, fmt.Errorf("expected a struct or a map but got %T", dest)
+	}
+	return v, nil
+}
+
+// setQuery sets `q` of the file `f` into `v`, returning whether it could be parsed
+func (ld *loading) setQuery(f string, q NamedQuery, v reflect.Value) bool {
+	if v.Type() != queryType {
+		v.SetString(q.SQL)
+		return true
+	}
+	parsed, err := ld.parseQuery(f, q.SQL)
+	if err != nil {
+		ld.errs.Invalid = append(ld.errs.Invalid, f+"#"+q.Name+": "+err.Error())
+		return false
+	}
+	v.Set(reflect.ValueOf(parsed))
+	return true
+}
+
+// LoadNamed loads the queries of `file`, delimited by `-- name: QueryName` markers,
+// into the fields of `dest` named after them, or named by their `sql` tag. `dest` can
+// also be a map of strings or Queries, receiving every query of the file, which is
+// allocated when `dest` points to a nil map. It returns a *LoadError listing every
+// missing query
+func LoadNamed(file string, queriesFS fs.FS, dest interface{}) error {
+	return Loader{}.LoadNamed(file, queriesFS, dest)
+}
+
+// LoadNamed loads queries like the package level LoadNamed. In strict mode, it also
+// rejects the queries of the file that have no matching field
+func (l Loader) LoadNamed(file string, queriesFS fs.FS, dest interface{}) error {
+	v, err := namedDest(dest)
+	if err != nil {
+		return err
+	}
+	ld := newLoading(queriesFS)
+	ld.readNamed(file, v)
+	if !l.Strict {
+		ld.errs.Unused = nil
+	}
+	return ld.errs.orNil()
+}
+
+// MustLoadNamed loads the queries of `file`
+func MustLoadNamed(file string, queriesFS fs.FS, dest interface{}) {
+	if err := LoadNamed(file, queriesFS, dest); err != nil {
+		log.Panic(errors.Wrap(err, "can't load queries"))
+	}
+}
@@ -0,0 +1,28 @@
+package file
+
+import "reflect"
+
+// Names returns the name of each query loaded into `dest` by its text. Queries are
+// named after the path of their field, like `Prices.GetAll`. Query fields are keyed
+// by their unrendered SQL, so the rendered text of a templated query isn't found and
+// it must be named with db.QueryName when it's run
+func Names(dest interface{}) map[string]string {
+	names := make(map[string]string)
+	addNames(reflect.Indirect(reflect.ValueOf(dest)), "", names)
+	return names
+}
+
+func addNames(v reflect.Value, prefix string, names map[string]string) {
+	for i := 0; i < v.NumField(); i++ {
+		name := prefix + v.Type().Field(i).Name
+		f := v.Field(i)
+		switch {
+		case f.Type() == queryType:
+			names[f.FieldByName("SQL").String()] = name
+		case f.Kind() == reflect.Struct:
+			addNames(f, name+".", names)
+		case f.Kind() == reflect.String:
+			names[f.String()] = name
+		}
+	}
+}
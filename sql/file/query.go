@@ -0,0 +1,144 @@
+package file
+
+import (
+	"bufio"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Param is a query parameter declared on a `-- params:` header as `name` or `name:type`
+type Param struct {
+	Name string
+	Type string
+}
+
+// Meta is the metadata declared on the header comments of a SQL file:
+//
+//	-- name: GetPrices
+//	-- timeout: 5s
+//	-- params: region:string, from:time.Time
+type Meta struct {
+	Name    string
+	Timeout time.Duration
+	Params  []Param
+	// Extra are the header keys without a field
+	Extra map[string]string
+}
+
+// ParseMeta parses the metadata declared on the leading comments of `query`
+func ParseMeta(query string) (Meta, error) {
+	var meta Meta
+	scanner := bufio.NewScanner(strings.NewReader(query))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "--") {
+			break
+		}
+		key, value, ok := header(line)
+		if !ok {
+			continue
+		}
+		if err := meta.set(key, value); err != nil {
+			return meta, err
+		}
+	}
+	return meta, scanner.Err()
+}
+
+// header returns the key and value of a `-- key: value` comment line
+func header(line string) (key, value string, ok bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "--") {
+		return "", "", false
+	}
+	key, value, ok = strings.Cut(strings.TrimPrefix(line, "--"), ":")
+	return strings.TrimSpace(key), strings.TrimSpace(value), ok
+}
+
+func (m *Meta) set(key, value string) error {
+	switch key {
+	case "name":
+		m.Name = value
+	case "timeout":
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("invalid timeout %q: %w", value, err)
+		}
+		m.Timeout = timeout
+	case "params":
+		m.Params = ParseParams(value)
+	default:
+		if m.Extra == nil {
+			m.Extra = make(map[string]string)
+		}
+		m.Extra[key] = value
+	}
+	return nil
+}
+
+// ParseParams parses a list of parameters like `region:string, from:time.Time`
+func ParseParams(list string) []Param {
+	var params []Param
+	for _, p := range strings.Split(list, ",") {
+		name, typ, _ := strings.Cut(p, ":")
+		if name = strings.TrimSpace(name); name != "" {
+			params = append(params, Param{Name: name, Type: strings.TrimSpace(typ)})
+		}
+	}
+	return params
+}
+
+// Query is a SQL file loaded with its metadata. Its text is a text/template
+// which can include other files of the same directory with `{{include "name" .}}`.
+// Included files are read and parsed when the query is loaded, so their names must
+// be constant strings
+type Query struct {
+	Meta
+	// SQL is the text of the file, before rendering
+	SQL  string
+	tmpl *template.Template
+}
+
+// nolint: gochecknoglobals
+var queryType = reflect.TypeOf(Query{})
+
+// String returns the text of the query
+func (q Query) String() string {
+	return q.SQL
+}
+
+// Render executes the template of the query with `data`
+func (q Query) Render(data interface{}) (string, error) {
+	if q.tmpl == nil {
+		return q.SQL, nil
+	}
+	var b strings.Builder
+	if err := q.tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// parseQuery parses the query of the file `f` with content `text`, along with the
+// files it includes
+func (ld *loading) parseQuery(f, text string) (Query, error) {
+	meta, err := ParseMeta(text)
+	if err != nil {
+		return Query{}, err
+	}
+	if meta.Name == "" {
+		meta.Name = strings.TrimSuffix(path.Base(f), ".sql")
+	}
+	tmpl, err := ld.parseTemplate(f, text, 0)
+	if err != nil {
+		return Query{}, err
+	}
+	return Query{Meta: meta, SQL: text, tmpl: tmpl}, nil
+}
@@ -0,0 +1,112 @@
+package file
+
+import (
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMeta(t *testing.T) {
+	meta, err := ParseMeta(`
+-- name: GetPrices
+-- timeout: 5s
+-- params: region:string, from:time.Time, limit
+-- owner: pricing
+SELECT * FROM prices
+-- name: ignored`)
+	require.NoError(t, err)
+	require.Equal(t, Meta{
+		Name:    "GetPrices",
+		Timeout: 5 * time.Second,
+		Params: []Param{
+			{Name: "region", Type: "string"},
+			{Name: "from", Type: "time.Time"},
+			{Name: "limit"},
+		},
+		Extra: map[string]string{"owner": "pricing"},
+	}, meta)
+
+	_, err = ParseMeta("-- timeout: soon\nSELECT 1")
+	require.Error(t, err)
+}
+
+func TestQueryRender(t *testing.T) {
+	fsys := fstest.MapFS{
+		"prices/filters.sql": {Data: []byte(`region = $1{{if .Spot}} AND spot{{end}}`)},
+		"prices/get.sql":     {Data: []byte("SELECT * FROM prices WHERE {{include \"filters\" .}}")},
+	}
+	text := string(fsys["prices/get.sql"].Data)
+	q, err := newLoading(fsys).parseQuery("prices/get.sql", text)
+	require.NoError(t, err)
+	require.Equal(t, "get", q.Name)
+
+	query, err := q.Render(map[string]bool{"Spot": true})
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM prices WHERE region = $1 AND spot", query)
+
+	_, err = q.Render(map[string]bool{})
+	require.Error(t, err)
+}
+
+func TestSplitQueries(t *testing.T) {
+	queries, err := SplitQueries(`-- prices queries
+
+-- name: GetAll
+SELECT * FROM prices;
+
+-- name: GetByID
+-- timeout: 1s
+SELECT * FROM prices WHERE id = $1;
+`)
+	require.NoError(t, err)
+	require.Equal(t, []NamedQuery{
+		{Name: "GetAll", SQL: "-- name: GetAll\nSELECT * FROM prices;"},
+		{
+			Name: "GetByID",
+			SQL:  "-- name: GetByID\n-- timeout: 1s\nSELECT * FROM prices WHERE id = $1;",
+		},
+	}, queries)
+
+	_, err = SplitQueries("-- name: A\nSELECT 1;\n-- name: A\nSELECT 2;")
+	require.Error(t, err)
+}
+
+func TestReadNamed(t *testing.T) {
+	fsys := fstest.MapFS{"prices.sql": {Data: []byte(
+		"-- name: GetAll\nSELECT * FROM prices;\n-- name: get-one\n-- timeout: 1s\nSELECT 1;\n" +
+			"-- name: Unused\nSELECT 2;",
+	)}}
+	var dest struct {
+		GetAll string
+		GetOne Query `sql:"get-one"`
+		Other  string
+	}
+	ld := newLoading(fsys)
+	ld.readNamed("prices.sql", reflect.ValueOf(&dest).Elem())
+	require.Equal(t, "-- name: GetAll\nSELECT * FROM prices;", dest.GetAll)
+	require.Equal(t, time.Second, dest.GetOne.Timeout)
+	require.Equal(t, []string{"prices.sql#Other"}, ld.errs.Missing)
+	require.Equal(t, []string{"prices.sql#Unused"}, ld.errs.Unused)
+
+	all := map[string]string{}
+	newLoading(fsys).readNamed("prices.sql", reflect.ValueOf(all))
+	require.Len(t, all, 3)
+}
+
+func TestLoadNamed(t *testing.T) {
+	fsys := fstest.MapFS{"prices.sql": {Data: []byte("-- name: GetAll\nSELECT * FROM prices;")}}
+	var queries map[string]Query
+	require.NoError(t, LoadNamed("prices.sql", fsys, &queries))
+	require.Equal(t, "GetAll", queries["GetAll"].Name)
+
+	var nilMap map[string]string
+	require.EqualError(t, LoadNamed("prices.sql", fsys, nilMap),
+		"can't load queries into a nil map[string]string")
+	require.EqualError(t, LoadNamed("prices.sql", fsys, map[string]int{}),
+		"expected a map of strings or Queries but got map[string]int")
+	require.EqualError(t, LoadNamed("prices.sql", fsys, struct{ GetAll string }{}),
+		"expected a pointer to a struct but got struct { GetAll string }")
+}
@@ -1,99 +1,209 @@
 package file
 
 import (
-	"embed"
 	"io/fs"
-	"path/filepath"
+	"path"
 	"reflect"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 
-	"github.com/agflow/tools/agerr"
 	"github.com/agflow/tools/log"
 )
 
-// mustRead is an utility method for MustReadSQL for sql files in queries subdirectory
-// Reads given folder and expects a SQL file named for each field of destination.
-// Then sets the content of file into that field.
-func mustRead(v reflect.Value, queriesFS embed.FS, subfolder string, base ...string) {
-	dir := subfolder
-	if len(base) > 0 {
-		dir = filepath.Join(base[0], subfolder)
+// LoadError lists every file that doesn't match the fields of the destination
+type LoadError struct {
+	// Missing are the files expected by fields that couldn't be read
+	Missing []string
+	// Unused are the files without a matching field, only reported in strict mode
+	Unused []string
+	// Invalid are the files of Query fields whose header or template can't be parsed
+	Invalid []string
+}
+
+func (e *LoadError) Error() string {
+	var msgs []string
+	if len(e.Missing) > 0 {
+		msgs = append(msgs, "missing sql files: "+strings.Join(e.Missing, ", "))
+	}
+	if len(e.Unused) > 0 {
+		msgs = append(msgs, "unused sql files: "+strings.Join(e.Unused, ", "))
+	}
+	if len(e.Invalid) > 0 {
+		msgs = append(msgs, "invalid sql files: "+strings.Join(e.Invalid, ", "))
 	}
+	return strings.Join(msgs, "; ")
+}
 
-	files, err := fs.ReadDir(queriesFS, dir)
+func (e *LoadError) orNil() error {
+	if len(e.Missing) == 0 && len(e.Unused) == 0 && len(e.Invalid) == 0 {
+		return nil
+	}
+	sort.Strings(e.Missing)
+	sort.Strings(e.Unused)
+	sort.Strings(e.Invalid)
+	return e
+}
+
+// Loader loads the content of sql files into the string or Query fields of a struct.
+// Only Query fields are parsed as templates, string fields keep the raw text
+type Loader struct {
+	// Strict rejects the sql files that have no matching field
+	Strict bool
+}
+
+// loading keeps track of the files read while loading queries
+type loading struct {
+	queriesFS fs.FS
+	errs      LoadError
+	// used are the files read by directory
+	used map[string]map[string]bool
+}
+
+func newLoading(queriesFS fs.FS) *loading {
+	return &loading{queriesFS: queriesFS, used: make(map[string]map[string]bool)}
+}
+
+// read sets the content of the file `f` into `v`, or records it as missing.
+// Query fields are parsed, recording the file as invalid when they can't be
+func (ld *loading) read(f string, v reflect.Value) {
+	f = path.Clean(f)
+	query, ok := ld.readFile(f)
+	if !ok {
+		return
+	}
+	if v.Type() != queryType {
+		v.SetString(query)
+		return
+	}
+	q, err := ld.parseQuery(f, query)
 	if err != nil {
-		panic(err)
+		ld.errs.Invalid = append(ld.errs.Invalid, f+": "+err.Error())
+		return
 	}
+	v.Set(reflect.ValueOf(q))
+}
 
-	got := make([]string, len(files))
-	for i, f := range files {
-		got[i] = strings.TrimSuffix(f.Name(), ".sql")
+// readFile returns the content of the file `f`, recording it as used or missing
+func (ld *loading) readFile(f string) (string, bool) {
+	content, err := ld.readUsed(f)
+	if err != nil {
+		ld.errs.Missing = append(ld.errs.Missing, f)
+		return "", false
 	}
+	return content, true
+}
 
-	if v.Kind() == reflect.Ptr {
-		v = v.Elem()
+// readUsed returns the content of the file `f`, recording it as used
+func (ld *loading) readUsed(f string) (string, error) {
+	dir, name := path.Split(f)
+	dir = path.Clean(dir)
+	if ld.used[dir] == nil {
+		ld.used[dir] = make(map[string]bool)
 	}
+	ld.used[dir][name] = true
 
-	names := make([]string, v.NumField())
-	for i := 0; i < v.NumField(); i++ {
-		names[i] = v.Type().Field(i).Name
+	content, err := fs.ReadFile(ld.queriesFS, f)
+	return string(content), err
+}
+
+// finish returns the errors found, checking for unused files if `strict` is set
+func (ld *loading) finish(strict bool) error {
+	if strict {
+		for dir, used := range ld.used {
+			files, err := fs.ReadDir(ld.queriesFS, dir)
+			if err != nil {
+				continue
+			}
+			for _, f := range files {
+				if !f.IsDir() && strings.HasSuffix(f.Name(), ".sql") && !used[f.Name()] {
+					ld.errs.Unused = append(ld.errs.Unused, path.Join(dir, f.Name()))
+				}
+			}
+		}
 	}
-	set(v, queriesFS, dir)
+	return ld.errs.orNil()
 }
 
-func set(v reflect.Value, queriesFS embed.FS, dir string) {
+// readDir expects a SQL file in `dir` named after each field of `v`
+func (ld *loading) readDir(v reflect.Value, dir string) {
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Type().Field(i)
-		f := filepath.Join(dir, field.Name+".sql")
-		query, err := fs.ReadFile(queriesFS, filepath.Clean(f))
-		if err != nil {
-			log.Fatal(errors.Wrapf(err, "can't read file %q", f))
-		}
-		v.FieldByName(field.Name).SetString(string(query))
+		ld.read(path.Join(dir, field.Name+".sql"), v.Field(i))
 	}
 }
 
-// mustReadFiles fills queries into Query variable.
-func mustReadFiles(root string, queriesFS embed.FS, query interface{}) {
-	q := reflect.ValueOf(query).Elem()
+// Load loads queries located in `root/<sql tag>/<field name>.sql` for each directory
+// `root` of `queriesFS` and each field of `query`. It returns a *LoadError listing
+// every missing file
+func Load(queriesFS fs.FS, query interface{}) error {
+	return Loader{}.Load(queriesFS, query)
+}
+
+// Load loads queries like the package level Load
+func (l Loader) Load(queriesFS fs.FS, query interface{}) error {
+	roots, err := fs.ReadDir(queriesFS, ".")
+	if err != nil {
+		return err
+	}
+	ld := newLoading(queriesFS)
 	v := reflect.Indirect(reflect.ValueOf(query))
-	for i := 0; i < v.NumField(); i++ {
-		f := v.Type().Field(i)
-		mustRead(q.FieldByName(f.Name), queriesFS,
-			filepath.Join(root, f.Tag.Get("sql")))
+	for _, r := range roots {
+		if !r.IsDir() {
+			continue
+		}
+		for i := 0; i < v.NumField(); i++ {
+			f := v.Type().Field(i)
+			ld.readDir(v.Field(i), path.Join(r.Name(), f.Tag.Get("sql")))
+		}
 	}
+	return ld.finish(l.Strict)
 }
 
 // MustLoad loads queries
 // that are located in the default sql directory
-func MustLoad(queriesFS embed.FS, query interface{}) {
-	f, err := fs.ReadDir(queriesFS, ".")
-	if err != nil {
-		log.Fatal(err)
-	}
-	for _, r := range f {
-		mustReadFiles(r.Name(), queriesFS, query)
+func MustLoad(queriesFS fs.FS, query interface{}) {
+	if err := Load(queriesFS, query); err != nil {
+		log.Fatal(errors.Wrap(err, "can't load queries"))
 	}
 }
 
-func setFile(dir string, queriesFS embed.FS, v reflect.Value) {
-	if v.Kind() == reflect.Struct {
-		MustLoadSQLFiles(dir+"/", queriesFS, v.Addr().Interface())
+func (ld *loading) setFile(file string, v reflect.Value) {
+	if v.Kind() == reflect.Struct && v.Type() != queryType {
+		ld.loadSQLFiles(file+"/", v)
 		return
 	}
-	file, err := queriesFS.ReadFile(dir + ".sql")
-	agerr.Assert(err)
-	v.SetString(string(file))
+	ld.read(file+".sql", v)
 }
 
-// MustLoadSQLFiles queries loads queries that are located on `dir`
-func MustLoadSQLFiles(dir string, queriesFS embed.FS, dest interface{}) {
-	v := reflect.Indirect(reflect.ValueOf(dest))
+func (ld *loading) loadSQLFiles(dir string, v reflect.Value) {
 	for i := 0; i < v.NumField(); i++ {
 		sField := v.Type().Field(i)
-		vField := v.FieldByName(sField.Name)
-		setFile(dir+sField.Tag.Get("sql"), queriesFS, vField)
+		ld.setFile(dir+sField.Tag.Get("sql"), v.Field(i))
+	}
+}
+
+// LoadSQLFiles loads queries that are located on `dir`, reading `<dir><sql tag>.sql`
+// for each field of `dest`, or the directory `<dir><sql tag>/` for struct fields.
+// It returns a *LoadError listing every missing file
+func LoadSQLFiles(dir string, queriesFS fs.FS, dest interface{}) error {
+	return Loader{}.LoadSQLFiles(dir, queriesFS, dest)
+}
+
+// LoadSQLFiles loads queries like the package level LoadSQLFiles
+func (l Loader) LoadSQLFiles(dir string, queriesFS fs.FS, dest interface{}) error {
+	ld := newLoading(queriesFS)
+	ld.loadSQLFiles(dir, reflect.Indirect(reflect.ValueOf(dest)))
+	return ld.finish(l.Strict)
+}
+
+// MustLoadSQLFiles queries loads queries that are located on `dir`
+func MustLoadSQLFiles(dir string, queriesFS fs.FS, dest interface{}) {
+	if err := LoadSQLFiles(dir, queriesFS, dest); err != nil {
+		log.Panic(errors.Wrap(err, "can't load queries"))
 	}
 }
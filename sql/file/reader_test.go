@@ -0,0 +1,110 @@
+package file
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+type priceQueries struct {
+	Prices struct {
+		Get  string
+		List Query
+	} `sql:"prices"`
+}
+
+type fileQueries struct {
+	Get    string `sql:"get"`
+	Prices struct {
+		List string `sql:"list"`
+	} `sql:"prices"`
+}
+
+func TestLoad(t *testing.T) {
+	queriesFS := fstest.MapFS{
+		"sql/prices/Get.sql":  {Data: []byte("SELECT * FROM prices WHERE id = $1")},
+		"sql/prices/List.sql": {Data: []byte("-- timeout: 5s\nSELECT * FROM prices")},
+	}
+	var queries priceQueries
+	require.NoError(t, Load(queriesFS, &queries))
+	require.Equal(t, "SELECT * FROM prices WHERE id = $1", queries.Prices.Get)
+	require.Equal(t, "List", queries.Prices.List.Name)
+	require.Equal(t, "-- timeout: 5s\nSELECT * FROM prices", queries.Prices.List.SQL)
+
+	queriesFS["sql/prices/Unused.sql"] = &fstest.MapFile{Data: []byte("SELECT 1")}
+	require.NoError(t, Load(queriesFS, &queries))
+	err := Loader{Strict: true}.Load(queriesFS, &queries)
+	var loadErr *LoadError
+	require.True(t, errors.As(err, &loadErr))
+	require.Equal(t, []string{"sql/prices/Unused.sql"}, loadErr.Unused)
+
+	queriesFS = fstest.MapFS{
+		"sql/prices/List.sql": {Data: []byte("-- timeout: soon\nSELECT * FROM prices")},
+	}
+	err = Load(queriesFS, &queries)
+	require.True(t, errors.As(err, &loadErr))
+	require.Equal(t, []string{"sql/prices/Get.sql"}, loadErr.Missing)
+	require.Len(t, loadErr.Invalid, 1)
+}
+
+func TestLoadSQLFiles(t *testing.T) {
+	queriesFS := fstest.MapFS{
+		"sql/get.sql":         {Data: []byte("SELECT * FROM prices WHERE id = $1")},
+		"sql/prices/list.sql": {Data: []byte("SELECT * FROM prices")},
+	}
+	var queries fileQueries
+	require.NoError(t, LoadSQLFiles("sql/", queriesFS, &queries))
+	require.Equal(t, "SELECT * FROM prices WHERE id = $1", queries.Get)
+	require.Equal(t, "SELECT * FROM prices", queries.Prices.List)
+
+	queriesFS["sql/prices/unused.sql"] = &fstest.MapFile{Data: []byte("SELECT 1")}
+	err := Loader{Strict: true}.LoadSQLFiles("sql/", queriesFS, &queries)
+	require.EqualError(t, err, "unused sql files: sql/prices/unused.sql")
+
+	err = LoadSQLFiles("missing/", queriesFS, &queries)
+	var loadErr *LoadError
+	require.True(t, errors.As(err, &loadErr))
+	require.Equal(t, []string{"missing/get.sql", "missing/prices/list.sql"}, loadErr.Missing)
+	require.EqualError(t, err,
+		"missing sql files: missing/get.sql, missing/prices/list.sql")
+}
+
+func TestNames(t *testing.T) {
+	var queries priceQueries
+	queries.Prices.Get = "SELECT * FROM prices WHERE id = $1"
+	queries.Prices.List = Query{SQL: "SELECT * FROM prices{{if .Spot}} WHERE spot{{end}}"}
+	require.Equal(t, map[string]string{
+		"SELECT * FROM prices WHERE id = $1":                 "Prices.Get",
+		"SELECT * FROM prices{{if .Spot}} WHERE spot{{end}}": "Prices.List",
+	}, Names(&queries))
+}
+
+func TestLoadIncludes(t *testing.T) {
+	queriesFS := fstest.MapFS{
+		"sql/filters.sql": {Data: []byte("region = $1")},
+		"sql/list.sql":    {Data: []byte(`SELECT * FROM prices WHERE {{include "filters" .}}`)},
+	}
+	var queries struct {
+		List Query `sql:"list"`
+	}
+	require.NoError(t, Loader{Strict: true}.LoadSQLFiles("sql/", queriesFS, &queries))
+	query, err := queries.List.Render(nil)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT * FROM prices WHERE region = $1", query)
+
+	queriesFS["sql/list.sql"] = &fstest.MapFile{Data: []byte(`SELECT {{include "nope" .}}`)}
+	err = LoadSQLFiles("sql/", queriesFS, &queries)
+	var loadErr *LoadError
+	require.True(t, errors.As(err, &loadErr))
+	require.Len(t, loadErr.Invalid, 1)
+	require.Contains(t, loadErr.Invalid[0], `sql/list.sql: can't include "nope"`)
+
+	queriesFS["sql/list.sql"] = &fstest.MapFile{Data: []byte(`SELECT {{include .Name .}}`)}
+	require.Error(t, LoadSQLFiles("sql/", queriesFS, &queries))
+
+	queriesFS["sql/list.sql"] = &fstest.MapFile{Data: []byte(`SELECT {{include "list" .}}`)}
+	err = LoadSQLFiles("sql/", queriesFS, &queries)
+	require.ErrorContains(t, err, "too many nested includes")
+}
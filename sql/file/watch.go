@@ -0,0 +1,103 @@
+package file
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"sync/atomic"
+	"time"
+
+	"github.com/agflow/tools/log"
+)
+
+// LoadFunc loads the queries of `queriesFS` into `dest`, like LoadSQLFiles
+type LoadFunc[T any] func(queriesFS fs.FS, dest *T) error
+
+// Live holds queries that can be reloaded while they're read
+type Live[T any] struct {
+	queries atomic.Value
+}
+
+// Get returns the last queries loaded. They must not be modified
+func (l *Live[T]) Get() *T {
+	return l.queries.Load().(*T)
+}
+
+// Watch loads the queries of `queriesFS` with `load`. When `interval` is positive,
+// the sql files are checked on every `interval` until `ctx` is done, and the queries
+// are reloaded when a file changes. A failed reload is logged and the previous queries
+// are kept. It's meant for development, with `queriesFS` being an os.DirFS
+func Watch[T any](
+	ctx context.Context, queriesFS fs.FS, interval time.Duration, load LoadFunc[T],
+) (*Live[T], error) {
+	live := &Live[T]{}
+	if err := live.reload(queriesFS, load); err != nil {
+		return nil, err
+	}
+	if interval <= 0 {
+		return live, nil
+	}
+	sum, err := checksum(queriesFS)
+	if err != nil {
+		return nil, err
+	}
+	go live.watch(ctx, queriesFS, interval, load, sum)
+	return live, nil
+}
+
+func (l *Live[T]) watch(
+	ctx context.Context, queriesFS fs.FS, interval time.Duration, load LoadFunc[T], sum uint64,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		current, err := checksum(queriesFS)
+		if err != nil {
+			log.Errorf("can't check sql files: %v", err)
+			continue
+		}
+		if current == sum {
+			continue
+		}
+		sum = current
+		if err := l.reload(queriesFS, load); err != nil {
+			log.Errorf("can't reload queries: %v", err)
+			continue
+		}
+		log.Info("queries reloaded")
+	}
+}
+
+// reload loads the queries into a new T, replacing the current ones on success
+func (l *Live[T]) reload(queriesFS fs.FS, load LoadFunc[T]) error {
+	queries := new(T)
+	if err := load(queriesFS, queries); err != nil {
+		return err
+	}
+	l.queries.Store(queries)
+	return nil
+}
+
+// checksum hashes the name, size and modification time of the sql files of `queriesFS`
+func checksum(queriesFS fs.FS) (uint64, error) {
+	h := fnv.New64a()
+	err := fs.WalkDir(queriesFS, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".sql" {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "%s:%d:%d\n", p, info.Size(), info.ModTime().UnixNano())
+		return nil
+	})
+	return h.Sum64(), err
+}
@@ -0,0 +1,41 @@
+package file
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatch(t *testing.T) {
+	type queries struct {
+		GetAll string `sql:"get_all"`
+	}
+	fsys := fstest.MapFS{"get_all.sql": {Data: []byte("SELECT 1")}}
+	load := func(queriesFS fs.FS, dest *queries) error {
+		return LoadSQLFiles("", queriesFS, dest)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	live, err := Watch(ctx, fsys, 0, load)
+	require.NoError(t, err)
+	require.Equal(t, "SELECT 1", live.Get().GetAll)
+
+	sum, err := checksum(fsys)
+	require.NoError(t, err)
+	fsys["get_all.sql"] = &fstest.MapFile{Data: []byte("SELECT 2"), ModTime: time.Now()}
+	current, err := checksum(fsys)
+	require.NoError(t, err)
+	require.NotEqual(t, sum, current)
+
+	require.NoError(t, live.reload(fsys, load))
+	require.Equal(t, "SELECT 2", live.Get().GetAll)
+
+	delete(fsys, "get_all.sql")
+	require.Error(t, live.reload(fsys, load))
+	require.Equal(t, "SELECT 2", live.Get().GetAll)
+}
@@ -0,0 +1,308 @@
+// Package gen generates typed Go functions from the queries of sql files.
+//
+// Each query declares its parameters and result columns on header comments:
+//
+//	-- name: GetPrices
+//	-- params: region:string, from:time.Time
+//	-- columns: id:int64, price:float64, date:time.Time
+//	-- returns: many
+//	SELECT id, price, date FROM prices WHERE region = $1 AND date >= $2
+//
+// `returns` is `many`, `one` or `exec`, and defaults to `many` when there are
+// columns and to `exec` otherwise. Placeholders without a declared parameter are
+// passed as interface{}
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/agflow/tools/sql/file"
+)
+
+// Returns values of the `-- returns:` header
+const (
+	ReturnsMany = "many"
+	ReturnsOne  = "one"
+	ReturnsExec = "exec"
+)
+
+// DefaultImports are the packages imported when their name prefixes a type
+// nolint: gochecknoglobals
+var DefaultImports = map[string]string{
+	"json": "encoding/json",
+	"sql":  "github.com/agflow/tools/sql",
+	"time": "time",
+}
+
+// Options configures the generated code
+type Options struct {
+	// Package is the name of the package of the generated file
+	Package string
+	// Imports maps the package names used by types to their import path,
+	// in addition to DefaultImports
+	Imports map[string]string
+}
+
+// Query is a query of a sql file, ready to be generated
+type Query struct {
+	// Name is the Go name of the query
+	Name    string
+	File    string
+	SQL     string
+	Returns string
+	Params  []file.Param
+	Columns []Column
+}
+
+// Column is a result column of a query
+type Column struct {
+	Field  string
+	Column string
+	Type   string
+}
+
+// Load reads the queries of every sql file of `fsys`
+func Load(fsys fs.FS) ([]Query, error) {
+	var queries []Query
+	err := fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || path.Ext(p) != ".sql" {
+			return err
+		}
+		content, err := fs.ReadFile(fsys, p)
+		if err != nil {
+			return err
+		}
+		fileQueries, err := Parse(p, string(content))
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		queries = append(queries, fileQueries...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(queries, func(i, j int) bool { return queries[i].Name < queries[j].Name })
+	for i := 1; i < len(queries); i++ {
+		if queries[i].Name == queries[i-1].Name {
+			return nil, fmt.Errorf("query %s declared twice", queries[i].Name)
+		}
+	}
+	return queries, nil
+}
+
+// Parse parses the queries of the file `f`, either delimited by `-- name:` markers
+// or a single query named after the file
+func Parse(f, text string) ([]Query, error) {
+	named, err := file.SplitQueries(text)
+	if err != nil {
+		return nil, err
+	}
+	if len(named) == 0 {
+		name := strings.TrimSuffix(path.Base(f), ".sql")
+		named = []file.NamedQuery{{Name: name, SQL: strings.TrimSpace(text)}}
+	}
+	queries := make([]Query, len(named))
+	for i, n := range named {
+		if queries[i], err = parseQuery(f, n); err != nil {
+			return nil, fmt.Errorf("query %s: %w", n.Name, err)
+		}
+	}
+	return queries, nil
+}
+
+func parseQuery(f string, n file.NamedQuery) (Query, error) {
+	if strings.Contains(n.SQL, "{{") {
+		return Query{}, fmt.Errorf("templated queries can't be generated")
+	}
+	meta, err := file.ParseMeta(n.SQL)
+	if err != nil {
+		return Query{}, err
+	}
+	q := Query{Name: GoName(n.Name), File: f, SQL: n.SQL}
+	if q.Columns, err = columns(meta.Extra["columns"]); err != nil {
+		return Query{}, err
+	}
+	if q.Params, err = params(meta.Params, Placeholders(n.SQL)); err != nil {
+		return Query{}, err
+	}
+	if q.Returns, err = returns(meta.Extra["returns"], len(q.Columns)); err != nil {
+		return Query{}, err
+	}
+	return q, nil
+}
+
+// columns parses the `-- columns:` header, every column must have a type
+func columns(list string) ([]Column, error) {
+	var cols []Column
+	for _, c := range file.ParseParams(list) {
+		if c.Type == "" {
+			return nil, fmt.Errorf("column %s without a type", c.Name)
+		}
+		cols = append(cols, Column{Field: GoName(c.Name), Column: c.Name, Type: c.Type})
+	}
+	return cols, nil
+}
+
+// returns checks the `-- returns:` header of a query with `n` columns, defaulting
+// it to ReturnsMany when there are columns and to ReturnsExec otherwise
+func returns(r string, n int) (string, error) {
+	if r == "" {
+		if n > 0 {
+			return ReturnsMany, nil
+		}
+		return ReturnsExec, nil
+	}
+	switch {
+	case r != ReturnsMany && r != ReturnsOne && r != ReturnsExec:
+		return "", fmt.Errorf("unknown returns %q", r)
+	case r != ReturnsExec && n == 0:
+		return "", fmt.Errorf("returns %s without columns", r)
+	}
+	return r, nil
+}
+
+// params names the `n` placeholders of a query after its declared parameters
+func params(declared []file.Param, n int) ([]file.Param, error) {
+	if len(declared) > n {
+		return nil, fmt.Errorf("%d params declared for %d placeholders", len(declared), n)
+	}
+	params := make([]file.Param, n)
+	for i := range params {
+		params[i] = file.Param{Name: fmt.Sprintf("arg%d", i+1), Type: "interface{}"}
+		if i < len(declared) {
+			params[i].Name = goParam(declared[i].Name)
+			if declared[i].Type != "" {
+				params[i].Type = declared[i].Type
+			}
+		}
+	}
+	return params, nil
+}
+
+// Generate returns the formatted Go source of the functions running `queries`
+func Generate(queries []Query, opts Options) ([]byte, error) {
+	var b bytes.Buffer
+	err := fileTemplate.Execute(&b, struct {
+		Package string
+		Imports importGroups
+		Queries []Query
+	}{opts.Package, imports(queries, opts.Imports), queries})
+	if err != nil {
+		return nil, err
+	}
+	return format.Source(b.Bytes())
+}
+
+// importGroups are the import paths of the standard library and of other packages
+type importGroups struct {
+	Std    []string
+	Others []string
+}
+
+// imports returns the import paths of the packages used by the types of `queries`
+func imports(queries []Query, extra map[string]string) importGroups {
+	known := make(map[string]string, len(DefaultImports)+len(extra))
+	for _, m := range []map[string]string{DefaultImports, extra} {
+		for name, p := range m {
+			known[name] = p
+		}
+	}
+	used := map[string]bool{"github.com/agflow/tools/sql/db": true}
+	for _, q := range queries {
+		for _, t := range q.types() {
+			pkg, _, ok := strings.Cut(strings.TrimLeft(t, "*[]"), ".")
+			if p, found := known[pkg]; ok && found {
+				used[p] = true
+			}
+		}
+	}
+	var groups importGroups
+	for p := range used {
+		if std(p) {
+			groups.Std = append(groups.Std, p)
+		} else {
+			groups.Others = append(groups.Others, p)
+		}
+	}
+	sort.Strings(groups.Std)
+	sort.Strings(groups.Others)
+	return groups
+}
+
+// types returns the types of the parameters and columns of `q`
+func (q Query) types() []string {
+	types := make([]string, 0, len(q.Params)+len(q.Columns))
+	for _, p := range q.Params {
+		types = append(types, p.Type)
+	}
+	for _, c := range q.Columns {
+		types = append(types, c.Type)
+	}
+	return types
+}
+
+// std returns whether `importPath` is a package of the standard library
+func std(importPath string) bool {
+	first, _, _ := strings.Cut(importPath, "/")
+	return !strings.Contains(first, ".")
+}
+
+// quote returns `s` as a Go string literal, raw when possible
+func quote(s string) string {
+	if strings.Contains(s, "`") {
+		return fmt.Sprintf("%q", s)
+	}
+	return "`" + s + "`"
+}
+
+// nolint: gochecknoglobals
+var fileTemplate = template.Must(template.New("file").
+	Funcs(template.FuncMap{"quote": quote, "lower": goParam}).
+	Parse(`// Code generated by sqlgen. DO NOT EDIT.
+
+package {{.Package}}
+
+import (
+{{- range .Imports.Std}}
+	"{{.}}"
+{{- end}}
+{{range .Imports.Others}}
+	"{{.}}"
+{{- end}}
+)
+{{range .Queries}}
+// {{lower .Name}}Query is the query {{.Name}} of {{.File}}
+const {{lower .Name}}Query = {{quote .SQL}}
+{{if .Columns}}
+// {{.Name}}Row is a row of {{.Name}}
+type {{.Name}}Row struct {
+{{- range .Columns}}
+	{{.Field}} {{.Type}} ` + "`db:\"{{.Column}}\"`" + `
+{{- end}}
+}
+{{end}}
+// {{.Name}} runs the query {{.Name}} of {{.File}}
+func {{.Name}}(svc db.Service{{range .Params}}, {{.Name}} {{.Type}}{{end}})
+{{- if eq .Returns "many"}} ([]{{.Name}}Row, error) {
+	var rows []{{.Name}}Row
+	err := svc.Select(&rows, {{lower .Name}}Query{{range .Params}}, {{.Name}}{{end}})
+	return rows, err
+}
+{{else if eq .Returns "one"}} ({{.Name}}Row, error) {
+	var row {{.Name}}Row
+	err := svc.Get(&row, {{lower .Name}}Query{{range .Params}}, {{.Name}}{{end}})
+	return row, err
+}
+{{else}} error {
+	return svc.Exec({{lower .Name}}Query{{range .Params}}, {{.Name}}{{end}})
+}
+{{end}}
+{{- end}}`))
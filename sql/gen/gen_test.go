@@ -0,0 +1,58 @@
+package gen
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlaceholders(t *testing.T) {
+	require.Equal(t, 0, Placeholders("SELECT '$1' -- $2\n/* $3 */"))
+	require.Equal(t, 3, Placeholders("SELECT $1::text, $3, $2, $1"))
+	require.Equal(t, 1, Placeholders("SELECT $fn$ $2 $fn$, $$ $3 $$ /* /* $4 */ $5 */, $1"))
+}
+
+func TestNames(t *testing.T) {
+	require.Equal(t, "ProductID", GoName("product_id"))
+	require.Equal(t, "GetPrices", GoName("get-prices"))
+	require.Equal(t, "productID", goParam("product_id"))
+	require.Equal(t, "idValue", goParam("id_value"))
+	require.Equal(t, "typeArg", goParam("type"))
+}
+
+func TestGenerate(t *testing.T) {
+	fsys := fstest.MapFS{
+		"prices.sql": {Data: []byte(`-- name: GetPrices
+-- params: region:string, from:time.Time
+-- columns: id:int64, price:sql.NullableFloat
+SELECT id, price FROM prices WHERE region = $1 AND date >= $2;
+
+-- name: get_price
+-- params: id:int64
+-- columns: price:float64
+-- returns: one
+SELECT price FROM prices WHERE id = $1;
+`)},
+		"delete_prices.sql": {Data: []byte("DELETE FROM prices WHERE region = $1")},
+	}
+	queries, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, queries, 3)
+
+	src, err := Generate(queries, Options{Package: "prices"})
+	require.NoError(t, err)
+	for _, s := range []string{
+		`"github.com/agflow/tools/sql"`,
+		`"time"`,
+		"func DeletePrices(svc db.Service, arg1 interface{}) error {",
+		"func GetPrice(svc db.Service, id int64) (GetPriceRow, error) {",
+		"func GetPrices(svc db.Service, region string, from time.Time) ([]GetPricesRow, error) {",
+		"Price sql.NullableFloat `db:\"price\"`",
+	} {
+		require.Contains(t, string(src), s)
+	}
+
+	_, err = Parse("bad.sql", "-- name: Bad\n-- params: a, b\nSELECT $1")
+	require.Error(t, err)
+}
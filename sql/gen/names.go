@@ -0,0 +1,88 @@
+package gen
+
+import (
+	"go/token"
+	"strings"
+	"unicode"
+
+	"github.com/agflow/tools/sql/internal/lexer"
+)
+
+// nolint: gochecknoglobals
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "ID": true, "JSON": true, "SQL": true, "URL": true, "UUID": true,
+}
+
+// reserved are the names used by the generated functions
+// nolint: gochecknoglobals
+var reserved = map[string]bool{"err": true, "row": true, "rows": true, "svc": true}
+
+// GoName returns the exported Go name of `name`, like `ProductID` for `product_id`
+func GoName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+	}
+	if s := b.String(); s != "" && !unicode.IsDigit(rune(s[0])) {
+		return s
+	}
+	return "Q" + b.String()
+}
+
+// goParam returns the unexported Go name of `name`, like `productID` for `product_id`
+func goParam(name string) string {
+	exported := GoName(name)
+	i := 0
+	for i < len(exported) && unicode.IsUpper(rune(exported[i])) {
+		i++
+	}
+	// keep the capital starting the next word, as in `IDs` -> `ids` but `IDValue` -> `idValue`
+	if i > 1 && i < len(exported) {
+		i--
+	}
+	if i == 0 {
+		i = 1
+	}
+	lower := strings.ToLower(exported[:i]) + exported[i:]
+	if token.IsKeyword(lower) || reserved[lower] {
+		return lower + "Arg"
+	}
+	return lower
+}
+
+// Placeholders returns the number of `$n` placeholders of `query`, the highest `n`,
+// skipping quoted text and comments
+func Placeholders(query string) int {
+	n := 0
+	for i := 0; i < len(query); i++ {
+		if skip := lexer.Skip(query, i); skip > 0 {
+			i += skip - 1
+			continue
+		}
+		if query[i] != '$' {
+			continue
+		}
+		var p int
+		p, i = placeholder(query, i+1)
+		if p > n {
+			n = p
+		}
+	}
+	return n
+}
+
+// placeholder parses the number of the placeholder whose digits start at `i` of
+// `query`, returning it with the index of its last digit
+func placeholder(query string, i int) (int, int) {
+	p := 0
+	for ; i < len(query) && query[i] >= '0' && query[i] <= '9'; i++ {
+		p = p*10 + int(query[i]-'0')
+	}
+	return p, i - 1
+}
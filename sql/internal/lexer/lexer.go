@@ -0,0 +1,83 @@
+// Package lexer scans the parts of a SQL query whose text must be kept as it is
+package lexer
+
+import "strings"
+
+// Skip returns the length of the quoted string, quoted identifier, dollar-quoted
+// string or comment starting at `i` of `query`, or 0 if there is none
+func Skip(query string, i int) int {
+	switch {
+	case query[i] == '\'' || query[i] == '"':
+		return quoted(query, i, query[i]) - i
+	case strings.HasPrefix(query[i:], "--"):
+		if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+			return end
+		}
+		return len(query) - i
+	case strings.HasPrefix(query[i:], "/*"):
+		return blockComment(query, i) - i
+	case query[i] == '$':
+		return dollarQuoted(query, i) - i
+	}
+	return 0
+}
+
+// quoted returns the index after the string quoted by `quote` starting at `start`
+func quoted(query string, start int, quote byte) int {
+	for i := start + 1; i < len(query); i++ {
+		if query[i] != quote {
+			continue
+		}
+		// a doubled quote is an escaped quote
+		if i+1 < len(query) && query[i+1] == quote {
+			i++
+			continue
+		}
+		return i + 1
+	}
+	return len(query)
+}
+
+// blockComment returns the index after the comment starting at `start`,
+// block comments being nested in postgres
+func blockComment(query string, start int) int {
+	depth := 0
+	for i := start; i < len(query)-1; i++ {
+		switch query[i : i+2] {
+		case "/*":
+			depth++
+			i++
+		case "*/":
+			depth--
+			i++
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(query)
+}
+
+// dollarQuoted returns the index after the string quoted by `$$` or `$tag$` starting
+// at `start`, or `start` if it doesn't start one, like the placeholder `$1`
+func dollarQuoted(query string, start int) int {
+	end := start + 1
+	for end < len(query) && IsIdentByte(query[end], end > start+1) {
+		end++
+	}
+	if end >= len(query) || query[end] != '$' {
+		return start
+	}
+	tag := query[start : end+1]
+	if j := strings.Index(query[end+1:], tag); j >= 0 {
+		return end + 1 + j + len(tag)
+	}
+	return len(query)
+}
+
+// IsIdentByte returns whether `c` can be part of an identifier, digits being only
+// allowed when `digits` is set, as identifiers can't start with them
+func IsIdentByte(c byte, digits bool) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' ||
+		digits && c >= '0' && c <= '9'
+}
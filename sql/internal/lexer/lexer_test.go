@@ -0,0 +1,25 @@
+package lexer
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSkip(t *testing.T) {
+	testCases := map[string]int{
+		"'it''s' AND":           7,
+		`"a ""b""" AND`:         9,
+		"-- comment\nAND":       10,
+		"/* a /* b */ c */ AND": 17,
+		"$$ a $ b $$ AND":       11,
+		"$fn$ $$ $fn$ AND":      12,
+		"'open":                 5,
+		"$1 AND":                0,
+		"$ AND":                 0,
+		"a AND":                 0,
+	}
+	for query, expected := range testCases {
+		require.Equal(t, expected, Skip(query, 0), query)
+	}
+}
@@ -0,0 +1,63 @@
+package sql
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// JSON is a value of T stored as json or jsonb, which can be null
+type JSON[T any] struct {
+	Val   T
+	Valid bool
+}
+
+// NewJSON creates a valid JSON
+func NewJSON[T any](v T) JSON[T] {
+	return JSON[T]{Val: v, Valid: true}
+}
+
+// Scan decodes the JSON of a json or jsonb column, NULL being invalid
+func (j *JSON[T]) Scan(value interface{}) error {
+	var zero T
+	j.Val, j.Valid = zero, false
+	var b []byte
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		return fmt.Errorf("can't scan %T into json", value)
+	}
+	if err := json.Unmarshal(b, &j.Val); err != nil {
+		return err
+	}
+	j.Valid = true
+	return nil
+}
+
+// Value encodes the value as JSON text, or NULL if invalid
+func (j JSON[T]) Value() (driver.Value, error) {
+	if !j.Valid {
+		return nil, nil
+	}
+	b, err := json.Marshal(j.Val)
+	if err != nil {
+		return nil, err
+	}
+	// a string, as lib/pq sends []byte as bytea
+	return string(b), nil
+}
+
+// MarshalJSON marshals JSON as its value or null
+func (j JSON[T]) MarshalJSON() ([]byte, error) {
+	return Nullable[T](j).MarshalJSON()
+}
+
+// UnmarshalJSON unmarshals JSON, null being invalid
+func (j *JSON[T]) UnmarshalJSON(data []byte) error {
+	return (*Nullable[T])(j).UnmarshalJSON(data)
+}
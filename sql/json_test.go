@@ -0,0 +1,33 @@
+package sql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSON(t *testing.T) {
+	type meta struct {
+		Source string `json:"source"`
+	}
+	var j JSON[meta]
+	require.NoError(t, j.Scan([]byte(`{"source":"cme"}`)))
+	require.Equal(t, NewJSON(meta{Source: "cme"}), j)
+
+	value, err := j.Value()
+	require.NoError(t, err)
+	require.Equal(t, `{"source":"cme"}`, value)
+
+	require.NoError(t, j.Scan(nil))
+	require.False(t, j.Valid)
+	value, err = j.Value()
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	b, err := json.Marshal(struct{ Meta JSON[meta] }{})
+	require.NoError(t, err)
+	require.Equal(t, `{"Meta":null}`, string(b))
+	require.NoError(t, json.Unmarshal([]byte(`{"source":"ice"}`), &j))
+	require.Equal(t, NewJSON(meta{Source: "ice"}), j)
+}
@@ -0,0 +1,354 @@
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/agflow/tools/log"
+	"github.com/agflow/tools/sql/db"
+)
+
+// DefaultTable is the table where applied migrations are tracked
+const DefaultTable = "schema_migrations"
+
+// nolint: gochecknoglobals
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// Migration is a versioned change of the schema, read from `NNN_name.up.sql`
+// and the optional `NNN_name.down.sql` files
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string
+	Checksum string
+}
+
+// Status is a migration with the time it was applied, if it was
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+}
+
+// applied is a row of the migrations table
+type applied struct {
+	Version   int64     `db:"version"`
+	Name      string    `db:"name"`
+	Checksum  string    `db:"checksum"`
+	AppliedAt time.Time `db:"applied_at"`
+}
+
+// Migrator applies the migrations of a directory to a database
+type Migrator struct {
+	svc        db.Service
+	table      string
+	lockID     int64
+	migrations []Migration
+}
+
+// New returns a Migrator of the migrations found in `dir` of `migrationsFS`
+// tracking them on DefaultTable
+func New(svc db.Service, migrationsFS fs.FS, dir string) (*Migrator, error) {
+	return NewWithTable(svc, migrationsFS, dir, DefaultTable)
+}
+
+// NewWithTable returns a Migrator of the migrations found in `dir` of `migrationsFS`
+// tracking them on `table`
+func NewWithTable(svc db.Service, migrationsFS fs.FS, dir, table string) (*Migrator, error) {
+	migrations, err := Load(migrationsFS, dir)
+	if err != nil {
+		return nil, err
+	}
+	h := fnv.New64a()
+	h.Write([]byte(table))
+	return &Migrator{
+		svc:        svc,
+		table:      table,
+		lockID:     int64(h.Sum64()),
+		migrations: migrations,
+	}, nil
+}
+
+// Load reads the migrations of `dir` sorted by version
+func Load(migrationsFS fs.FS, dir string) ([]Migration, error) {
+	files, err := fs.ReadDir(migrationsFS, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, f := range files {
+		match := fileName.FindStringSubmatch(f.Name())
+		if f.IsDir() || match == nil {
+			continue
+		}
+		if err := readFile(migrationsFS, dir, match, byVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// readFile reads the migration file whose name matched `fileName` into `byVersion`
+func readFile(
+	migrationsFS fs.FS, dir string, match []string, byVersion map[int64]*Migration,
+) error {
+	version, err := strconv.ParseInt(match[1], 10, 64)
+	if err != nil {
+		return errors.Wrapf(err, "invalid version of %q", match[0])
+	}
+	m, ok := byVersion[version]
+	if !ok {
+		m = &Migration{Version: version, Name: match[2]}
+		byVersion[version] = m
+	}
+	if m.Name != match[2] {
+		return fmt.Errorf("version %d is used by %q and %q", version, m.Name, match[2])
+	}
+
+	content, err := fs.ReadFile(migrationsFS, path.Join(dir, match[0]))
+	if err != nil {
+		return errors.Wrapf(err, "can't read file %q", match[0])
+	}
+	if match[3] == "up" {
+		m.Up = string(content)
+		m.Checksum = fmt.Sprintf("%x", sha256.Sum256(content))
+	} else {
+		m.Down = string(content)
+	}
+	return nil
+}
+
+// Up applies every pending migration
+func (m *Migrator) Up(ctx context.Context) error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.To(ctx, m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the last applied migration
+func (m *Migrator) Down(ctx context.Context) error {
+	_, err := m.step(ctx, func(done []applied) (*Migration, error) {
+		if len(done) == 0 {
+			return nil, nil
+		}
+		return m.last(done)
+	})
+	return err
+}
+
+// To applies or reverts migrations until `version` is the last one applied
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	for {
+		ok, err := m.step(ctx, func(done []applied) (*Migration, error) {
+			return m.next(done, version)
+		})
+		if err != nil || !ok {
+			return err
+		}
+	}
+}
+
+// next returns the migration to run to get closer to `version`
+func (m *Migrator) next(done []applied, version int64) (*Migration, error) {
+	if len(done) > 0 && done[len(done)-1].Version > version {
+		return m.last(done)
+	}
+	isDone := make(map[int64]bool, len(done))
+	for _, a := range done {
+		isDone[a.Version] = true
+	}
+	for i := range m.migrations {
+		if m.migrations[i].Version > version {
+			break
+		}
+		if !isDone[m.migrations[i].Version] {
+			return &m.migrations[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// last returns the last applied migration, which must have a file to be reverted
+func (m *Migrator) last(done []applied) (*Migration, error) {
+	a := done[len(done)-1]
+	if migration := m.find(a.Version); migration != nil {
+		return migration, nil
+	}
+	return nil, fmt.Errorf("applied migration %d_%s has no file to revert it", a.Version, a.Name)
+}
+
+// Status returns every migration known by files or by the database
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var done []applied
+	err := m.locked(ctx, func(tx db.Service) error {
+		var err error
+		done, err = m.applied(tx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]Status, len(m.migrations))
+	for i := range m.migrations {
+		statuses[i] = Status{Migration: m.migrations[i]}
+	}
+	for _, a := range done {
+		if i := m.index(a.Version); i >= 0 {
+			statuses[i].Applied = true
+			statuses[i].AppliedAt = a.AppliedAt
+			continue
+		}
+		statuses = append(statuses, Status{
+			Migration: Migration{Version: a.Version, Name: a.Name, Checksum: a.Checksum},
+			Applied:   true,
+			AppliedAt: a.AppliedAt,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+	return statuses, nil
+}
+
+// locked runs `fn` in a transaction holding the migrations lock, so that concurrent
+// migrators wait for each other, once the migrations table exists
+func (m *Migrator) locked(ctx context.Context, fn func(db.Service) error) error {
+	return m.svc.WithTx(ctx, nil, func(tx db.Service) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock($1)", m.lockID); err != nil {
+			return err
+		}
+		if err := m.createTable(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// step applies, or reverts if it's applied, the migration picked by `pick` given
+// the applied ones. It returns false when there was nothing to run
+func (m *Migrator) step(
+	ctx context.Context, pick func([]applied) (*Migration, error),
+) (bool, error) {
+	ran := false
+	err := m.locked(ctx, func(tx db.Service) error {
+		done, err := m.applied(tx)
+		if err != nil {
+			return err
+		}
+		if err := m.verify(done); err != nil {
+			return err
+		}
+
+		migration, err := pick(done)
+		if err != nil || migration == nil {
+			return err
+		}
+		ran = true
+		if !m.isApplied(done, migration.Version) {
+			return m.up(tx, migration)
+		}
+		return m.down(tx, migration)
+	})
+	return ran, err
+}
+
+func (m *Migrator) up(tx db.Service, migration *Migration) error {
+	log.Infof("applying migration %d_%s", migration.Version, migration.Name)
+	if err := tx.Exec(migration.Up); err != nil {
+		return errors.Wrapf(err, "can't apply migration %d_%s", migration.Version, migration.Name)
+	}
+	return tx.Exec(
+		fmt.Sprintf("INSERT INTO %s (version, name, checksum) VALUES ($1, $2, $3)", m.table),
+		migration.Version, migration.Name, migration.Checksum)
+}
+
+func (m *Migrator) down(tx db.Service, migration *Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+	}
+	log.Infof("reverting migration %d_%s", migration.Version, migration.Name)
+	if err := tx.Exec(migration.Down); err != nil {
+		return errors.Wrapf(err, "can't revert migration %d_%s", migration.Version, migration.Name)
+	}
+	return tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE version = $1", m.table), migration.Version)
+}
+
+// verify checks that the applied migrations weren't changed since they were applied
+func (m *Migrator) verify(done []applied) error {
+	for _, a := range done {
+		migration := m.find(a.Version)
+		if migration == nil {
+			log.Warnf("applied migration %d_%s has no file", a.Version, a.Name)
+			continue
+		}
+		if migration.Checksum != a.Checksum {
+			return fmt.Errorf("migration %d_%s changed after being applied",
+				a.Version, a.Name)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) createTable(tx db.Service) error {
+	return tx.Exec(fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+	version bigint PRIMARY KEY,
+	name text NOT NULL,
+	checksum text NOT NULL,
+	applied_at timestamptz NOT NULL DEFAULT now()
+)`, m.table))
+}
+
+func (m *Migrator) applied(svc db.Service) ([]applied, error) {
+	var done []applied
+	err := svc.Select(&done, fmt.Sprintf(
+		"SELECT version, name, checksum, applied_at FROM %s ORDER BY version", m.table))
+	return done, err
+}
+
+func (m *Migrator) isApplied(done []applied, version int64) bool {
+	for _, a := range done {
+		if a.Version == version {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Migrator) index(version int64) int {
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+func (m *Migrator) find(version int64) *Migration {
+	if i := m.index(version); i >= 0 {
+		return &m.migrations[i]
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/agflow/tools/sql/db"
+	"github.com/agflow/tools/sql/db/dbtest"
+)
+
+func TestLoad(t *testing.T) {
+	migrationsFS := fstest.MapFS{
+		"migrations/002_add_region.up.sql":      {Data: []byte("ALTER TABLE prices ADD r text;")},
+		"migrations/002_add_region.down.sql":    {Data: []byte("ALTER TABLE prices DROP r;")},
+		"migrations/001_create_prices.up.sql":   {Data: []byte("CREATE TABLE prices (id int);")},
+		"migrations/001_create_prices.down.sql": {Data: []byte("DROP TABLE prices;")},
+		"migrations/README.md":                  {Data: []byte("ignored")},
+	}
+
+	migrations, err := Load(migrationsFS, "migrations")
+	require.NoError(t, err)
+	require.Len(t, migrations, 2)
+	require.Equal(t, int64(1), migrations[0].Version)
+	require.Equal(t, "create_prices", migrations[0].Name)
+	require.Equal(t, "DROP TABLE prices;", migrations[0].Down)
+	require.Equal(t, int64(2), migrations[1].Version)
+	require.NotEmpty(t, migrations[1].Checksum)
+
+	migrationsFS["migrations/003_no_up.down.sql"] = &fstest.MapFile{Data: []byte("SELECT 1;")}
+	_, err = Load(migrationsFS, "migrations")
+	require.Error(t, err)
+}
+
+func testMigrator(svc db.Service) *Migrator {
+	return &Migrator{svc: svc, table: DefaultTable, lockID: 1, migrations: []Migration{
+		{Version: 1, Name: "create_prices", Up: "CREATE TABLE prices;", Down: "DROP TABLE prices;",
+			Checksum: "c1"},
+		{Version: 2, Name: "add_region", Up: "ALTER TABLE prices ADD r text;", Checksum: "c2"},
+		{Version: 3, Name: "add_date", Up: "ALTER TABLE prices ADD d date;", Checksum: "c3"},
+	}}
+}
+
+func TestNext(t *testing.T) {
+	m := testMigrator(nil)
+	testCases := map[string]struct {
+		done     []int64
+		version  int64
+		expected int64
+		err      bool
+	}{
+		"first":           {version: 3, expected: 1},
+		"pending":         {done: []int64{1}, version: 3, expected: 2},
+		"gap":             {done: []int64{1, 3}, version: 3, expected: 2},
+		"target reached":  {done: []int64{1, 2}, version: 2},
+		"up to date":      {done: []int64{1, 2, 3}, version: 3},
+		"revert":          {done: []int64{1, 2, 3}, version: 1, expected: 3},
+		"revert no file":  {done: []int64{1, 4}, version: 3, err: true},
+		"before any file": {version: 0},
+	}
+	for name, testCase := range testCases {
+		done := make([]applied, len(testCase.done))
+		for i, v := range testCase.done {
+			done[i] = applied{Version: v}
+		}
+		migration, err := m.next(done, testCase.version)
+		if testCase.err {
+			require.Error(t, err, name)
+			continue
+		}
+		require.NoError(t, err, name)
+		if testCase.expected == 0 {
+			require.Nil(t, migration, name)
+			continue
+		}
+		require.Equal(t, testCase.expected, migration.Version, name)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	m := testMigrator(nil)
+	require.NoError(t, m.verify([]applied{{Version: 1, Checksum: "c1"}, {Version: 9}}))
+	require.Error(t, m.verify([]applied{{Version: 1, Checksum: "changed"}}))
+}
+
+const appliedQuery = "SELECT version, name, checksum, applied_at FROM schema_migrations " +
+	"ORDER BY version"
+
+func appliedRow(version int64, name, checksum string) []interface{} {
+	return []interface{}{version, name, checksum, time.Now()}
+}
+
+func TestStep(t *testing.T) {
+	fake := dbtest.New()
+	fake.Expect(appliedQuery).WillReturnRows(
+		[]string{"version", "name", "checksum", "applied_at"},
+		appliedRow(1, "create_prices", "c1"))
+	m := testMigrator(fake)
+
+	ran, err := m.step(context.Background(), func(done []applied) (*Migration, error) {
+		return m.next(done, 2)
+	})
+	require.NoError(t, err)
+	require.True(t, ran)
+	executed := fake.Executed()
+	require.Len(t, executed, 5)
+	require.Equal(t, "SELECT pg_advisory_xact_lock($1)", executed[0].Query)
+	require.Contains(t, executed[1].Query, "CREATE TABLE IF NOT EXISTS schema_migrations")
+	require.Equal(t, "ALTER TABLE prices ADD r text;", executed[3].Query)
+	require.Equal(t, []interface{}{int64(2), "add_region", "c2"}, executed[4].Args)
+
+	require.NoError(t, m.Down(context.Background()))
+	executed = fake.Executed()[5:]
+	require.Equal(t, "DROP TABLE prices;", executed[3].Query)
+	require.Equal(t, "DELETE FROM schema_migrations WHERE version = $1", executed[4].Query)
+}
+
+func TestDownWithoutFile(t *testing.T) {
+	fake := dbtest.New()
+	fake.Expect(appliedQuery).WillReturnRows(
+		[]string{"version", "name", "checksum", "applied_at"},
+		appliedRow(1, "create_prices", "c1"), appliedRow(4, "removed", "c4"))
+	m := testMigrator(fake)
+
+	require.EqualError(t, m.Down(context.Background()),
+		"applied migration 4_removed has no file to revert it")
+	require.EqualError(t, m.To(context.Background(), 1),
+		"applied migration 4_removed has no file to revert it")
+}
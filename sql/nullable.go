@@ -0,0 +1,244 @@
+package sql
+
+import (
+	q "database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Nullable is a value of T which can be null, in the database and in JSON
+type Nullable[T any] struct {
+	Val   T
+	Valid bool
+}
+
+// NullableBool is a nullable bool
+type NullableBool = Nullable[bool]
+
+// NullableInt32 is a nullable int32
+type NullableInt32 = Nullable[int32]
+
+// NullableBytes is a nullable []byte
+type NullableBytes = Nullable[[]byte]
+
+// NullableUUID is a nullable UUID
+type NullableUUID = Nullable[UUID]
+
+// NullableDecimal is a nullable Decimal
+type NullableDecimal = Nullable[Decimal]
+
+// NewNullable creates a valid Nullable
+func NewNullable[T any](v T) Nullable[T] {
+	return Nullable[T]{Val: v, Valid: true}
+}
+
+// Scan implements sql.Scanner
+func (n *Nullable[T]) Scan(value interface{}) error {
+	var zero T
+	n.Val, n.Valid = zero, false
+	if value == nil {
+		return nil
+	}
+	if err := Assign(&n.Val, value); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// Value implements driver.Valuer
+func (n Nullable[T]) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	if valuer, ok := interface{}(n.Val).(driver.Valuer); ok {
+		return valuer.Value()
+	}
+	return driver.DefaultParameterConverter.ConvertValue(n.Val)
+}
+
+// MarshalJSON marshals Nullable as its value or null
+func (n Nullable[T]) MarshalJSON() ([]byte, error) {
+	if !n.Valid {
+		return []byte("null"), nil
+	}
+	return json.Marshal(n.Val)
+}
+
+// UnmarshalJSON unmarshals Nullable, null being invalid
+func (n *Nullable[T]) UnmarshalJSON(data []byte) error {
+	// Unmarshalling into a pointer will let us detect null
+	var x *T
+	if err := json.Unmarshal(data, &x); err != nil {
+		return err
+	}
+	if x == nil {
+		var zero T
+		n.Val, n.Valid = zero, false
+		return nil
+	}
+	n.Val, n.Valid = *x, true
+	return nil
+}
+
+// MarshalText marshals Nullable as the text of its value, or an empty text if invalid
+func (n Nullable[T]) MarshalText() ([]byte, error) {
+	if !n.Valid {
+		return []byte{}, nil
+	}
+	switch v := interface{}(n.Val).(type) {
+	case encoding.TextMarshaler:
+		return v.MarshalText()
+	case []byte:
+		return v, nil
+	}
+	return []byte(fmt.Sprint(n.Val)), nil
+}
+
+// UnmarshalText unmarshals Nullable, an empty text being invalid
+func (n *Nullable[T]) UnmarshalText(text []byte) error {
+	var zero T
+	n.Val, n.Valid = zero, false
+	if len(text) == 0 {
+		return nil
+	}
+	if u, ok := interface{}(&n.Val).(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText(text); err != nil {
+			return err
+		}
+	} else if err := Assign(&n.Val, string(text)); err != nil {
+		return err
+	}
+	n.Valid = true
+	return nil
+}
+
+// NullableBind binds a Nullable to its value, or to the zero value of T if invalid
+func NullableBind[T any](v reflect.Value) interface{} {
+	n, ok := v.Interface().(Nullable[T])
+	if !ok || !n.Valid {
+		var zero T
+		return zero
+	}
+	return n.Val
+}
+
+// Assign sets `src`, a value read from the database or parsed from text, into the
+// pointer `dest` like sql.Rows.Scan does: Scanners scan it, NULL sets the zero value,
+// text is copied into byte slices or parsed into strings, bools and numbers, and
+// numbers are converted when it doesn't lose precision
+func Assign(dest, src interface{}) error {
+	if scanner, ok := dest.(q.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	d := reflect.ValueOf(dest)
+	if d.Kind() != reflect.Ptr || d.IsNil() {
+		return errors.New("destination not a pointer")
+	}
+	d = d.Elem()
+	switch {
+	case src == nil:
+		d.Set(reflect.Zero(d.Type()))
+		return nil
+	case d.Kind() == reflect.Ptr:
+		v := reflect.New(d.Type().Elem())
+		if err := Assign(v.Interface(), src); err != nil {
+			return err
+		}
+		d.Set(v)
+		return nil
+	}
+	switch s := src.(type) {
+	case []byte:
+		return assignText(d, string(s))
+	case string:
+		return assignText(d, s)
+	}
+	return assignValue(d, reflect.ValueOf(src))
+}
+
+// assignText copies `s` into `d` if it's a byte slice, or parses it
+func assignText(d reflect.Value, s string) error {
+	if d.Kind() == reflect.Slice && d.Type().Elem().Kind() == reflect.Uint8 {
+		d.SetBytes([]byte(s))
+		return nil
+	}
+	return assignString(d, s)
+}
+
+// assignValue sets `s` into `d`, converting numbers of the same kind without overflow
+// and integers into floats
+func assignValue(d, s reflect.Value) error {
+	switch {
+	case s.Type().AssignableTo(d.Type()):
+		d.Set(s)
+		return nil
+	case kindClass(s.Kind()) == reflect.Int && kindClass(d.Kind()) == reflect.Float64:
+		d.SetFloat(float64(s.Int()))
+		return nil
+	case kindClass(s.Kind()) != kindClass(d.Kind()) || !s.Type().ConvertibleTo(d.Type()):
+		return fmt.Errorf("can't assign %s to %s", s.Type(), d.Type())
+	case overflows(d, s):
+		return fmt.Errorf("%v overflows %s", s.Interface(), d.Type())
+	}
+	d.Set(s.Convert(d.Type()))
+	return nil
+}
+
+// overflows returns whether `s` doesn't fit in `d`, a number of the same kind
+func overflows(d, s reflect.Value) bool {
+	switch kindClass(d.Kind()) {
+	case reflect.Int:
+		return d.OverflowInt(s.Int())
+	case reflect.Uint:
+		return d.OverflowUint(s.Uint())
+	case reflect.Float64:
+		return d.OverflowFloat(s.Float())
+	}
+	return false
+}
+
+// assignString parses `s` into `d` according to its kind
+func assignString(d reflect.Value, s string) error {
+	var (
+		v   interface{}
+		err error
+	)
+	switch kindClass(d.Kind()) {
+	case reflect.String:
+		v = s
+	case reflect.Bool:
+		v, err = strconv.ParseBool(s)
+	case reflect.Int:
+		v, err = strconv.ParseInt(s, 10, d.Type().Bits())
+	case reflect.Uint:
+		v, err = strconv.ParseUint(s, 10, d.Type().Bits())
+	case reflect.Float64:
+		v, err = strconv.ParseFloat(s, d.Type().Bits())
+	default:
+		return fmt.Errorf("can't assign a string to %s", d.Type())
+	}
+	if err != nil {
+		return err
+	}
+	d.Set(reflect.ValueOf(v).Convert(d.Type()))
+	return nil
+}
+
+// kindClass groups the kinds that can be converted into each other without loss
+func kindClass(k reflect.Kind) reflect.Kind {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return reflect.Int
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.Uint
+	case reflect.Float32, reflect.Float64:
+		return reflect.Float64
+	}
+	return k
+}
@@ -0,0 +1,84 @@
+package sql
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNullable(t *testing.T) {
+	var n NullableInt32
+	require.NoError(t, n.Scan(int64(12)))
+	require.Equal(t, NewNullable(int32(12)), n)
+	require.Error(t, n.Scan(int64(1)<<40))
+	require.NoError(t, n.Scan(nil))
+	require.False(t, n.Valid)
+
+	var b NullableBool
+	require.NoError(t, b.Scan([]byte("true")))
+	require.True(t, b.Val)
+	value, err := b.Value()
+	require.NoError(t, err)
+	require.Equal(t, true, value)
+
+	var bytes NullableBytes
+	require.NoError(t, json.Unmarshal([]byte(`null`), &bytes))
+	require.False(t, bytes.Valid)
+	text, err := NewNullable([]byte("abc")).MarshalText()
+	require.NoError(t, err)
+	require.Equal(t, "abc", string(text))
+
+	var f Nullable[float64]
+	require.NoError(t, f.UnmarshalText([]byte("1.5")))
+	require.Equal(t, 1.5, NullableBind[float64](reflect.ValueOf(f)))
+	require.NoError(t, f.UnmarshalText(nil))
+	require.Equal(t, 0.0, NullableBind[float64](reflect.ValueOf(f)))
+}
+
+func TestNullableUUIDAndDecimal(t *testing.T) {
+	var u NullableUUID
+	require.NoError(t, u.Scan([]byte("a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11")))
+	b, err := json.Marshal(u)
+	require.NoError(t, err)
+	require.Equal(t, `"a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"`, string(b))
+	require.Error(t, u.Scan("not-a-uuid"))
+
+	var d NullableDecimal
+	require.NoError(t, d.Scan([]byte("12345678901234567890.123")))
+	b, err = json.Marshal(d)
+	require.NoError(t, err)
+	require.Equal(t, `12345678901234567890.123`, string(b))
+	require.NoError(t, json.Unmarshal([]byte(`"NaN"`), &d))
+	require.Equal(t, Decimal("NaN"), d.Val)
+}
+
+func TestNullableCompat(t *testing.T) {
+	var s NullableString
+	require.NoError(t, json.Unmarshal([]byte(`"NaN"`), &s))
+	require.False(t, s.Valid)
+	b, err := json.Marshal(struct {
+		S NullableString
+		I NullableInt
+		F NullableFloat
+	}{NewNullString("a"), NullableInt{}, NewNullFloat(1.5)})
+	require.NoError(t, err)
+	require.Equal(t, `{"S":"a","I":null,"F":1.5}`, string(b))
+}
+
+func TestAssign(t *testing.T) {
+	var p *float64
+	require.NoError(t, Assign(&p, int64(2)))
+	require.Equal(t, 2.0, *p)
+	require.NoError(t, Assign(&p, nil))
+	require.Nil(t, p)
+
+	var s string
+	require.NoError(t, Assign(&s, []byte("br")))
+	require.Equal(t, "br", s)
+
+	var i int64
+	require.Error(t, Assign(&i, 1.5))
+	require.Error(t, Assign(i, int64(1)))
+}
@@ -1,10 +1,15 @@
 package sql
 
 import (
+	"bytes"
 	q "database/sql"
+	"database/sql/driver"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"reflect"
+	"time"
+
+	"github.com/lib/pq"
 )
 
 // NullableString returns similar to NullString from database/sql package
@@ -19,26 +24,20 @@ func NewNullString(s string) NullableString {
 
 // MarshalJSON marshals NullableString
 func (v NullableString) MarshalJSON() ([]byte, error) {
-	if v.Valid {
-		return json.Marshal(v.String)
-	}
-	return json.Marshal(nil)
+	return Nullable[string]{Val: v.String, Valid: v.Valid}.MarshalJSON()
 }
 
-// UnmarshalJSON unmarshals NullableString
-func (v *NullableString) UnmarshalJSON(data []byte) error { // nolint: dupl
-	// Unmarshalling into a pointer will let us detect null
-	var x *string
-	if err := json.Unmarshal(data, &x); err != nil {
+// UnmarshalJSON unmarshals NullableString, "NaN" being invalid
+func (v *NullableString) UnmarshalJSON(data []byte) error {
+	var n Nullable[string]
+	if err := n.UnmarshalJSON(data); err != nil {
 		return err
 	}
-	if x == nil || *x == "NaN" {
+	if !n.Valid || n.Val == "NaN" {
 		v.Valid = false
 		return nil
 	}
-
-	v.Valid = true
-	v.String = *x
+	v.Valid, v.String = true, n.Val
 	return nil
 }
 
@@ -63,25 +62,19 @@ func NewNullInt(v int64) NullableInt {
 
 // MarshalJSON marshals NullableInt
 func (v NullableInt) MarshalJSON() ([]byte, error) {
-	if v.Valid {
-		return json.Marshal(v.Int64)
-	}
-	return json.Marshal(nil)
+	return Nullable[int64]{Val: v.Int64, Valid: v.Valid}.MarshalJSON()
 }
 
 // UnmarshalJSON unmarshal NullableInt
-func (v *NullableInt) UnmarshalJSON(data []byte) error { // nolint: dupl
-	// Unmarshalling into a pointer will let us detect null
-	var x *int64
-	if err := json.Unmarshal(data, &x); err != nil {
+func (v *NullableInt) UnmarshalJSON(data []byte) error {
+	var n Nullable[int64]
+	if err := n.UnmarshalJSON(data); err != nil {
 		return err
 	}
-	if x != nil {
-		v.Valid = true
-		v.Int64 = *x
-	} else {
-		v.Valid = false
+	if n.Valid {
+		v.Int64 = n.Val
 	}
+	v.Valid = n.Valid
 	return nil
 }
 
@@ -101,66 +94,154 @@ type NullableFloat struct {
 
 // MarshalJSON marshals NullableFloat
 func (v NullableFloat) MarshalJSON() ([]byte, error) {
-	if v.Valid {
-		return json.Marshal(v.Float64)
-	}
-	return json.Marshal(nil)
+	return Nullable[float64]{Val: v.Float64, Valid: v.Valid}.MarshalJSON()
 }
 
 // UnmarshalJSON unmarshal NullableFloat
-func (v *NullableFloat) UnmarshalJSON(data []byte) error { // nolint: dupl
-	// Unmarshalling into a pointer will let us detect null
-	var x *float64
-	if err := json.Unmarshal(data, &x); err != nil {
+func (v *NullableFloat) UnmarshalJSON(data []byte) error {
+	var n Nullable[float64]
+	if err := n.UnmarshalJSON(data); err != nil {
 		return err
 	}
-	if x != nil {
-		v.Valid = true
-		v.Float64 = *x
-	} else {
-		v.Valid = false
+	if n.Valid {
+		v.Float64 = n.Val
 	}
+	v.Valid = n.Valid
 	return nil
 }
 
-// Ints is array of int64
+// NullableFloatBind binds to nullable float
+func NullableFloatBind(v reflect.Value) interface{} {
+	n, ok := v.Interface().(NullableFloat)
+	if !ok || !n.Valid {
+		return 0.0
+	}
+	return n.Float64
+}
+
+// Ints is array of int64, scanned from a postgres array or a JSON array.
+// Like the other arrays, its NULL elements are scanned as zero values
 type Ints []int64
 
 // Scan scans Ints
 func (ns *Ints) Scan(value interface{}) error {
-	switch v := value.(type) {
-	case []byte:
-		return json.Unmarshal(v, &ns)
-	case nil:
-		return json.Unmarshal([]byte("null"), &ns)
-	}
-	return errors.New("type assertion to []byte failed")
+	return scanValues(value, (*[]int64)(ns))
+}
+
+// Value returns Ints as a postgres array
+func (ns Ints) Value() (driver.Value, error) {
+	return pq.Int64Array(ns).Value()
 }
 
-// Strings is array of string
+// Strings is array of string, scanned from a postgres array or a JSON array
 type Strings []string
 
 // Scan scans Strings
 func (ss *Strings) Scan(value interface{}) error {
-	switch v := value.(type) {
-	case []byte:
-		return json.Unmarshal(v, &ss)
-	case nil:
-		return json.Unmarshal([]byte("null"), &ss)
-	}
-	return errors.New("type assertion []byte failed")
+	return scanValues(value, (*[]string)(ss))
 }
 
-// Floats is array of float64
+// Value returns Strings as a postgres array
+func (ss Strings) Value() (driver.Value, error) {
+	return pq.StringArray(ss).Value()
+}
+
+// Floats is array of float64, scanned from a postgres array or a JSON array
 type Floats []float64
 
 // Scan scans Floats
 func (fs *Floats) Scan(value interface{}) error {
-	b, ok := value.([]byte)
-	if !ok {
-		return errors.New("type assertion to []byte failed")
+	return scanValues(value, (*[]float64)(fs))
+}
+
+// Value returns Floats as a postgres array
+func (fs Floats) Value() (driver.Value, error) {
+	return pq.Float64Array(fs).Value()
+}
+
+// Bools is array of bool, scanned from a postgres array or a JSON array
+type Bools []bool
+
+// Scan scans Bools
+func (bs *Bools) Scan(value interface{}) error {
+	return scanValues(value, (*[]bool)(bs))
+}
+
+// Value returns Bools as a postgres array
+func (bs Bools) Value() (driver.Value, error) {
+	return pq.BoolArray(bs).Value()
+}
+
+// Times is array of time.Time, scanned from a postgres array or a JSON array
+type Times []time.Time
+
+// Scan scans Times
+func (ts *Times) Scan(value interface{}) error {
+	var texts []NullableString
+	if ok, err := scanArray(value, (*[]time.Time)(ts), &texts); !ok || err != nil {
+		return err
+	}
+	times := make(Times, len(texts))
+	for i, text := range texts {
+		if !text.Valid {
+			continue
+		}
+		t, err := pq.ParseTimestamp(nil, text.String)
+		if err != nil {
+			return err
+		}
+		times[i] = t
+	}
+	*ts = times
+	return nil
+}
+
+// Value returns Times as a postgres array
+func (ts Times) Value() (driver.Value, error) {
+	if ts == nil {
+		return nil, nil
+	}
+	texts := make(pq.StringArray, len(ts))
+	for i, t := range ts {
+		texts[i] = t.Format(time.RFC3339Nano)
+	}
+	return texts.Value()
+}
+
+// scanValues scans `value`, a JSON array or a postgres array, into `dest`.
+// NULL elements are scanned as the zero value of T
+func scanValues[T any](value interface{}, dest *[]T) error {
+	var elems []Nullable[T]
+	if ok, err := scanArray(value, dest, &elems); !ok || err != nil {
+		return err
+	}
+	values := make([]T, len(elems))
+	for i := range elems {
+		values[i] = elems[i].Val
+	}
+	*dest = values
+	return nil
+}
+
+// scanArray scans `value`, a JSON array into `jsonDest` or a postgres array into
+// `elems`, a pointer to a slice of Scanners receiving nil for NULL elements.
+// It returns whether `value` was a postgres array
+func scanArray(value, jsonDest, elems interface{}) (bool, error) {
+	var b []byte
+	switch v := value.(type) {
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	case nil:
+		return false, json.Unmarshal([]byte("null"), jsonDest)
+	default:
+		return false, fmt.Errorf("can't scan %T into an array", value)
+	}
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '[' {
+		return false, json.Unmarshal(trimmed, jsonDest)
 	}
-	return json.Unmarshal(b, &fs)
+	return true, pq.GenericArray{A: elems}.Scan(b)
 }
 
 // NewNullFloat creates a valid NullableFloat
@@ -0,0 +1,55 @@
+package sql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrays(t *testing.T) {
+	var ints Ints
+	require.NoError(t, ints.Scan([]byte("{1,2,3}")))
+	require.Equal(t, Ints{1, 2, 3}, ints)
+	require.NoError(t, ints.Scan([]byte("[4, 5]")))
+	require.Equal(t, Ints{4, 5}, ints)
+	require.NoError(t, ints.Scan([]byte("{1,NULL}")))
+	require.Equal(t, Ints{1, 0}, ints)
+	require.NoError(t, ints.Scan([]byte("[1, null]")))
+	require.Equal(t, Ints{1, 0}, ints)
+	require.Error(t, ints.Scan([]byte("{1,a}")))
+	require.NoError(t, ints.Scan(nil))
+	require.Nil(t, ints)
+
+	var strs Strings
+	require.NoError(t, strs.Scan(`{a,"b c","d\"e",""}`))
+	require.Equal(t, Strings{"a", "b c", `d"e`, ""}, strs)
+	value, err := strs.Value()
+	require.NoError(t, err)
+	require.Equal(t, `{"a","b c","d\"e",""}`, value)
+	require.NoError(t, strs.Scan(`{a,NULL,"NULL"}`))
+	require.Equal(t, Strings{"a", "", "NULL"}, strs)
+
+	var floats Floats
+	require.NoError(t, floats.Scan([]byte("{1.5,-2}")))
+	require.Equal(t, Floats{1.5, -2}, floats)
+
+	var bools Bools
+	require.NoError(t, bools.Scan([]byte("{t,f}")))
+	require.Equal(t, Bools{true, false}, bools)
+
+	var times Times
+	require.NoError(t, times.Scan([]byte(`{"2022-01-02 03:04:05+00","2022-01-03 00:00:00.5+02"}`)))
+	require.Len(t, times, 2)
+	require.True(t, times[0].Equal(time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)))
+	require.True(t, times[1].Equal(time.Date(2022, 1, 2, 22, 0, 0, 5e8, time.UTC)))
+	require.NoError(t, times.Scan([]byte(`{NULL}`)))
+	require.Equal(t, Times{{}}, times)
+	value, err = Times{time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)}.Value()
+	require.NoError(t, err)
+	require.Equal(t, `{"2022-01-02T03:04:05Z"}`, value)
+
+	value, err = Ints(nil).Value()
+	require.NoError(t, err)
+	require.Nil(t, value)
+}